@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/intervals"
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// MAX_SYNC_VALUES bounds how many values one digest exchange moves each
+// way, so a digest covering a huge span cannot stall the node. Whatever is
+// left over is exchanged in later rounds.
+const MAX_SYNC_VALUES = 10000
+
+// syncBody carries a digest of the sender's seen set as inclusive ranges.
+type syncBody struct {
+	Type   string            `json:"type"`
+	Ranges []intervals.Range `json:"ranges"`
+}
+
+func (svc *broadcastSvc) digest() []intervals.Range {
+	svc.msgLock.RLock()
+	defer svc.msgLock.RUnlock()
+	return svc.seen.Ranges()
+}
+
+// gaps returns values covered by the digest that were not seen yet.
+func (svc *broadcastSvc) gaps(digest []intervals.Range) []int {
+	svc.msgLock.RLock()
+	defer svc.msgLock.RUnlock()
+	return svc.seen.Gaps(digest, MAX_SYNC_VALUES)
+}
+
+// missing returns seen values that the given digest does not cover.
+func (svc *broadcastSvc) missing(digest []intervals.Range) []int {
+	svc.msgLock.RLock()
+	defer svc.msgLock.RUnlock()
+	return svc.seen.Except(digest, MAX_SYNC_VALUES)
+}
+
+// reconcile swaps digests with one random peer: the peer learns our values
+// from the digest and replies with the values we are missing.
+func (svc *broadcastSvc) reconcile(n *maelstrom.Node, s spreader, peers []string) {
+	if len(peers) == 0 {
+		return
+	}
+	dst := peers[rand.Intn(len(peers))]
+
+	n.RPC(dst, syncBody{Type: "sync", Ranges: svc.digest()}, func(msg maelstrom.Message) error {
+		var body gossipBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		svc.fd.heard(msg.Src)
+		fresh := svc.addAll(body.Messages)
+		if len(fresh) > 0 {
+			log.Printf("Pulled %d missing values from node %s", len(fresh), msg.Src)
+		}
+		s.spread(fresh, msg.Src)
+		return nil
+	})
+}
+
+// antiEntropy reconciles with one of the peers every interval.
+func (svc *broadcastSvc) antiEntropy(n *maelstrom.Node, s spreader, peers func() []string, interval time.Duration) {
+	for range time.Tick(interval) {
+		svc.reconcile(n, s, peers())
+	}
+}
+
+func handleSync(n *maelstrom.Node, svc *broadcastSvc, s spreader) maelstrom.HandlerFunc {
+	return func(msg maelstrom.Message) error {
+		var body syncBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		if err := intervals.Validate(body.Ranges); err != nil {
+			return maelstrom.NewRPCError(maelstrom.MalformedRequest, err.Error())
+		}
+		svc.fd.heard(msg.Src)
+
+		// the digest is lossless, so the caller's values can be taken from it directly
+		fresh := svc.addAll(svc.gaps(body.Ranges))
+		if len(fresh) > 0 {
+			log.Printf("Learned %d values from digest of node %s", len(fresh), msg.Src)
+		}
+		s.spread(fresh, msg.Src)
+
+		return n.Reply(msg, gossipBody{
+			Type:     "sync_ok",
+			Messages: svc.missing(body.Ranges),
+		})
+	}
+}
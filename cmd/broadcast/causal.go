@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/rtt"
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// stamped is a value tagged by its origin with a per-origin sequence number
+// and the vector clock the origin had delivered when it broadcast the value.
+type stamped struct {
+	Origin string         `json:"origin"`
+	Seq    int            `json:"seq"`
+	Deps   map[string]int `json:"deps"`
+	Value  int            `json:"value"`
+}
+
+type causalBody struct {
+	Type    string    `json:"type"`
+	Entries []stamped `json:"entries"`
+}
+
+type causalSyncBody struct {
+	Type  string         `json:"type"`
+	Clock map[string]int `json:"clock"`
+}
+
+// causal delivers values in an order that respects causality: a value is
+// held back until every value its origin had delivered before broadcasting
+// it was delivered here too. Entries are flooded along the topology and
+// resent to a neighbor until it acknowledges them; exchanging vector clocks
+// with neighbors covers entries of nodes that were never our neighbors.
+type causal struct {
+	n         *maelstrom.Node
+	svc       *broadcastSvc
+	mu        sync.Mutex
+	nbrs      []string
+	clock     map[string]int       // delivered count per origin
+	history   map[string][]stamped // delivered entries per origin, by seq
+	held      map[string]map[int]stamped
+	delivered []int
+	entries   map[int]stamped    // delivered entries by value
+	pending   map[string]*outbox // values of entries per neighbor
+	rtts      map[string]*rtt.Estimator
+}
+
+func createCausal(n *maelstrom.Node, svc *broadcastSvc) *causal {
+	return &causal{
+		n:         n,
+		svc:       svc,
+		nbrs:      make([]string, 0),
+		clock:     make(map[string]int),
+		history:   make(map[string][]stamped),
+		held:      make(map[string]map[int]stamped),
+		delivered: make([]int, 0),
+		entries:   make(map[int]stamped),
+		pending:   make(map[string]*outbox),
+		rtts:      make(map[string]*rtt.Estimator),
+	}
+}
+
+// box returns the outbox of a neighbor. Must be called with mu held.
+func (c *causal) box(nbr string) *outbox {
+	if _, ok := c.pending[nbr]; !ok {
+		c.pending[nbr] = newOutbox()
+		c.rtts[nbr] = rtt.NewEstimator(RTO_INIT_MILL*time.Millisecond, RTO_MIN_MILL*time.Millisecond, RETRY_MILL*time.Millisecond)
+	}
+	return c.pending[nbr]
+}
+
+// setNeighbors hands entries queued for dropped neighbors to the new ones.
+func (c *causal) setNeighbors(nbrs []string) {
+	c.svc.setNeighbors(nbrs)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	heirs := without(nbrs, c.nbrs)
+	if len(heirs) == 0 {
+		heirs = nbrs
+	}
+	for _, nbr := range without(c.nbrs, nbrs) {
+		if box, ok := c.pending[nbr]; ok {
+			values := box.all()
+			for _, heir := range heirs {
+				for _, v := range values {
+					c.box(heir).push(v)
+				}
+			}
+		}
+		delete(c.pending, nbr)
+		delete(c.rtts, nbr)
+	}
+	c.nbrs = nbrs
+}
+
+// broadcast stamps a client value and delivers it locally right away.
+func (c *causal) broadcast(v int) {
+	if !c.svc.add(v) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	self := c.n.ID()
+	deps := make(map[string]int, len(c.clock))
+	for origin, seq := range c.clock {
+		deps[origin] = seq
+	}
+	c.deliver(stamped{Origin: self, Seq: c.clock[self] + 1, Deps: deps, Value: v}, "")
+}
+
+func (c *causal) read() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp := make([]int, len(c.delivered))
+	copy(cp, c.delivered)
+	return cp
+}
+
+// deliverable reports whether everything e depends on was delivered.
+// Must be called with mu held.
+func (c *causal) deliverable(e stamped) bool {
+	if c.clock[e.Origin] != e.Seq-1 {
+		return false
+	}
+	for origin, seq := range e.Deps {
+		if origin != e.Origin && c.clock[origin] < seq {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver exposes e to reads and forwards it to neighbors other than src.
+// Must be called with mu held.
+func (c *causal) deliver(e stamped, src string) {
+	c.clock[e.Origin] = e.Seq
+	c.history[e.Origin] = append(c.history[e.Origin], e)
+	c.delivered = append(c.delivered, e.Value)
+	c.entries[e.Value] = e
+	c.svc.add(e.Value)
+
+	for _, nbr := range c.nbrs {
+		if nbr != src {
+			c.box(nbr).push(e.Value)
+		}
+	}
+}
+
+// receive holds back entries and delivers every one that became deliverable.
+func (c *causal) receive(entries []stamped, src string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range entries {
+		if e.Seq <= c.clock[e.Origin] {
+			continue
+		}
+		if c.held[e.Origin] == nil {
+			c.held[e.Origin] = make(map[int]stamped)
+		}
+		c.held[e.Origin][e.Seq] = e
+	}
+
+	for progress := true; progress; {
+		progress = false
+		for origin, waiting := range c.held {
+			e, ok := waiting[c.clock[origin]+1]
+			if !ok || !c.deliverable(e) {
+				continue
+			}
+			delete(waiting, e.Seq)
+			if len(waiting) == 0 {
+				delete(c.held, origin)
+			}
+			c.deliver(e, src)
+			progress = true
+		}
+	}
+}
+
+// since returns delivered entries that a node with the given clock lacks.
+func (c *causal) since(clock map[string]int) []stamped {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]stamped, 0)
+	for origin, history := range c.history {
+		if seq := clock[origin]; seq < len(history) {
+			entries = append(entries, history[seq:]...)
+		}
+	}
+	return entries
+}
+
+// flush sends queued entries and resends those a neighbor did not
+// acknowledge in time.
+func (c *causal) flush() {
+	type causalBatch struct {
+		values     []int
+		entries    []stamped
+		retransmit bool
+	}
+
+	now := time.Now()
+	batches := make(map[string]causalBatch)
+
+	c.mu.Lock()
+	for nbr, box := range c.pending {
+		est := c.rtts[nbr]
+		values, retransmit := box.batch(now, est.RTO())
+		if retransmit {
+			est.Timeout()
+		}
+		if len(values) == 0 {
+			continue
+		}
+		entries := make([]stamped, len(values))
+		for i, v := range values {
+			entries[i] = c.entries[v]
+		}
+		batches[nbr] = causalBatch{values: values, entries: entries, retransmit: retransmit}
+	}
+	c.mu.Unlock()
+
+	for dst, batch := range batches {
+		dst, batch := dst, batch
+		c.n.RPC(dst, causalBody{Type: "causal", Entries: batch.entries}, func(msg maelstrom.Message) error {
+			c.ack(dst, batch.values, now, batch.retransmit)
+			return nil
+		})
+	}
+}
+
+// ack clears acknowledged entries; boxes of dropped neighbors are gone.
+func (c *causal) ack(nbr string, values []int, sent time.Time, retransmit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if box, ok := c.pending[nbr]; ok {
+		box.ack(values)
+		if !retransmit {
+			c.rtts[nbr].Observe(time.Since(sent))
+		}
+	}
+}
+
+// reconcile sends our clock to a random neighbor, which replies with the
+// entries we have not delivered yet.
+func (c *causal) reconcile() {
+	c.mu.Lock()
+	if len(c.nbrs) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	dst := c.nbrs[rand.Intn(len(c.nbrs))]
+	clock := make(map[string]int, len(c.clock))
+	for origin, seq := range c.clock {
+		clock[origin] = seq
+	}
+	c.mu.Unlock()
+
+	c.n.RPC(dst, causalSyncBody{Type: "causal_sync", Clock: clock}, func(msg maelstrom.Message) error {
+		var body causalBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		if len(body.Entries) > 0 {
+			log.Printf("Pulled %d causal entries from node %s", len(body.Entries), msg.Src)
+		}
+		c.receive(body.Entries, msg.Src)
+		return nil
+	})
+}
+
+func (c *causal) run(tick time.Duration, syncInterval time.Duration) {
+	go func() {
+		for range time.Tick(syncInterval) {
+			c.reconcile()
+		}
+	}()
+	for range time.Tick(tick) {
+		c.flush()
+	}
+}
+
+func (c *causal) register(n *maelstrom.Node) {
+	n.Handle("causal", func(msg maelstrom.Message) error {
+		var body causalBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		c.receive(body.Entries, msg.Src)
+		return n.Reply(msg, map[string]string{"type": "causal_ok"})
+	})
+
+	n.Handle("causal_sync", func(msg maelstrom.Message) error {
+		var body causalSyncBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		return n.Reply(msg, causalBody{
+			Type:    "causal_sync_ok",
+			Entries: c.since(body.Clock),
+		})
+	})
+}
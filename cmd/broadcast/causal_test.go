@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+func testNode(id string, ids []string) *maelstrom.Node {
+	n := maelstrom.NewNode()
+	n.Init(id, ids)
+	n.Stdout = io.Discard
+	return n
+}
+
+func testCausal() *causal {
+	return createCausal(testNode("n1", []string{"n1", "n2", "n3", "n4"}), createBroadcastSvc(time.Second))
+}
+
+func TestCausalReceive(t *testing.T) {
+	deps := func(kv ...any) map[string]int {
+		d := make(map[string]int)
+		for i := 0; i < len(kv); i += 2 {
+			d[kv[i].(string)] = kv[i+1].(int)
+		}
+		return d
+	}
+	type args struct {
+		batches [][]stamped
+	}
+	tests := []struct {
+		name string
+		args args
+		want [][]int // delivered values after every batch
+	}{
+		{
+			name: "in order",
+			args: args{batches: [][]stamped{
+				{{Origin: "n2", Seq: 1, Value: 10}},
+				{{Origin: "n2", Seq: 2, Deps: deps("n2", 1), Value: 11}},
+			}},
+			want: [][]int{{10}, {10, 11}},
+		},
+		{
+			name: "later entry of an origin waits for the earlier one",
+			args: args{batches: [][]stamped{
+				{{Origin: "n2", Seq: 2, Deps: deps("n2", 1), Value: 11}},
+				{{Origin: "n2", Seq: 1, Value: 10}},
+			}},
+			want: [][]int{{}, {10, 11}},
+		},
+		{
+			name: "entry waits for what its origin had delivered",
+			args: args{batches: [][]stamped{
+				{{Origin: "n3", Seq: 1, Deps: deps("n2", 2), Value: 20}},
+				{{Origin: "n2", Seq: 1, Value: 10}},
+				{{Origin: "n2", Seq: 2, Deps: deps("n2", 1), Value: 11}},
+			}},
+			want: [][]int{{}, {10}, {10, 11, 20}},
+		},
+		{
+			name: "duplicates are delivered once",
+			args: args{batches: [][]stamped{
+				{{Origin: "n2", Seq: 1, Value: 10}, {Origin: "n2", Seq: 1, Value: 10}},
+				{{Origin: "n2", Seq: 1, Value: 10}},
+			}},
+			want: [][]int{{10}, {10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testCausal()
+
+			got := make([][]int, 0)
+			for _, batch := range tt.args.batches {
+				c.receive(batch, "n2")
+				got = append(got, c.read())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCausalBroadcastDependsOnDelivered(t *testing.T) {
+	c := testCausal()
+	c.receive([]stamped{{Origin: "n2", Seq: 1, Value: 10}}, "n2")
+	c.broadcast(30)
+	c.broadcast(31)
+
+	got := c.since(map[string]int{"n2": 1})
+	want := []stamped{
+		{Origin: "n1", Seq: 1, Deps: map[string]int{"n2": 1}, Value: 30},
+		{Origin: "n1", Seq: 2, Deps: map[string]int{"n1": 1, "n2": 1}, Value: 31},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("since() = %v, want %v", got, want)
+	}
+}
+
+func TestCausalResendsUntilAcknowledged(t *testing.T) {
+	c := testCausal()
+	c.setNeighbors([]string{"n2", "n3"})
+	c.receive([]stamped{{Origin: "n2", Seq: 1, Value: 10}}, "n2")
+	c.broadcast(30)
+
+	// nothing is forwarded back to the neighbor an entry came from
+	sizes := func() map[string]int {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		s := make(map[string]int)
+		for nbr, box := range c.pending {
+			s[nbr] = box.size()
+		}
+		return s
+	}
+	if got, want := sizes(), map[string]int{"n2": 1, "n3": 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending = %v, want %v", got, want)
+	}
+
+	// sent entries stay pending until acknowledged
+	c.flush()
+	if got, want := sizes(), map[string]int{"n2": 1, "n3": 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending after flush = %v, want %v", got, want)
+	}
+	c.ack("n3", []int{10, 30}, time.Now(), false)
+	if got, want := sizes(), map[string]int{"n2": 1, "n3": 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pending after ack = %v, want %v", got, want)
+	}
+
+	// unacknowledged entries of a dropped neighbor go to its replacement
+	c.setNeighbors([]string{"n3", "n4"})
+	if got, want := sizes(), map[string]int{"n3": 0, "n4": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pending after rebuild = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+type readSinceBody struct {
+	Type   string `json:"type"`
+	Cursor string `json:"cursor"`
+}
+
+type readSinceResBody struct {
+	Type     string `json:"type"`
+	Messages []int  `json:"messages"`
+	Cursor   string `json:"cursor"`
+}
+
+// cursor points into the arrival log of one node process. Clients treat it
+// as an opaque string.
+type cursor struct {
+	node   string
+	boot   string
+	offset int
+}
+
+func (c cursor) encode() string {
+	raw := fmt.Sprintf("%s/%s/%d", c.node, c.boot, c.offset)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, fmt.Errorf("malformed cursor %q", s)
+	}
+	parts := strings.Split(string(raw), "/")
+	if len(parts) != 3 {
+		return cursor{}, fmt.Errorf("malformed cursor %q", s)
+	}
+	offset, err := strconv.Atoi(parts[2])
+	if err != nil || offset < 0 {
+		return cursor{}, fmt.Errorf("malformed cursor %q", s)
+	}
+	return cursor{node: parts[0], boot: parts[1], offset: offset}, nil
+}
+
+// since returns the values that arrived after the cursor, together with a
+// cursor for the next call. An empty cursor, or one issued by another node
+// or an earlier process, starts from the beginning of the log.
+func (svc *broadcastSvc) since(node string, raw string) ([]int, string, error) {
+	from := 0
+	if raw != "" {
+		c, err := decodeCursor(raw)
+		if err != nil {
+			return nil, "", err
+		}
+		if c.node == node && c.boot == svc.boot {
+			from = c.offset
+		}
+	}
+
+	svc.msgLock.RLock()
+	if from > len(svc.arrivals) {
+		from = len(svc.arrivals)
+	}
+	values := make([]int, len(svc.arrivals)-from)
+	copy(values, svc.arrivals[from:])
+	next := cursor{node: node, boot: svc.boot, offset: len(svc.arrivals)}
+	svc.msgLock.RUnlock()
+
+	return values, next.encode(), nil
+}
+
+func handleReadSince(n *maelstrom.Node, svc *broadcastSvc) maelstrom.HandlerFunc {
+	return func(msg maelstrom.Message) error {
+		var body readSinceBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		values, next, err := svc.since(n.ID(), body.Cursor)
+		if err != nil {
+			return maelstrom.NewRPCError(maelstrom.MalformedRequest, err.Error())
+		}
+
+		return n.Reply(msg, readSinceResBody{
+			Type:     "read_since_ok",
+			Messages: values,
+			Cursor:   next,
+		})
+	}
+}
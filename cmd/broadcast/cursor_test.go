@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    cursor
+		wantErr bool
+	}{
+		{
+			name: "round trip",
+			raw:  cursor{node: "n1", boot: "abc", offset: 42}.encode(),
+			want: cursor{node: "n1", boot: "abc", offset: 42},
+		},
+		{
+			name:    "not base64",
+			raw:     "%%%",
+			wantErr: true,
+		},
+		{
+			name:    "missing part",
+			raw:     base64.RawURLEncoding.EncodeToString([]byte("n1/abc")),
+			wantErr: true,
+		},
+		{
+			name:    "negative offset",
+			raw:     cursor{node: "n1", boot: "abc", offset: -1}.encode(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("decodeCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSince(t *testing.T) {
+	svc := createBroadcastSvc(time.Second)
+	svc.addAll([]int{5, 3, 9})
+	at := func(node, boot string, offset int) string {
+		return cursor{node: node, boot: boot, offset: offset}.encode()
+	}
+
+	type args struct {
+		node string
+		raw  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []int
+	}{
+		{
+			name: "empty cursor reads everything",
+			args: args{node: "n1", raw: ""},
+			want: []int{5, 3, 9},
+		},
+		{
+			name: "cursor of this process reads what arrived after it",
+			args: args{node: "n1", raw: at("n1", svc.boot, 1)},
+			want: []int{3, 9},
+		},
+		{
+			name: "cursor of another node starts over",
+			args: args{node: "n1", raw: at("n2", svc.boot, 2)},
+			want: []int{5, 3, 9},
+		},
+		{
+			name: "cursor of an earlier process starts over",
+			args: args{node: "n1", raw: at("n1", "earlier", 2)},
+			want: []int{5, 3, 9},
+		},
+		{
+			name: "offset past the end is clamped",
+			args: args{node: "n1", raw: at("n1", svc.boot, 10)},
+			want: []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, next, err := svc.since(tt.args.node, tt.args.raw)
+			if err != nil {
+				t.Fatalf("since() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("since() = %v, want %v", got, tt.want)
+			}
+			if want := at(tt.args.node, svc.boot, 3); next != want {
+				t.Errorf("since() cursor = %q, want %q", next, want)
+			}
+		})
+	}
+}
+
+func TestSinceAdvances(t *testing.T) {
+	svc := createBroadcastSvc(time.Second)
+	batches := [][]int{{1, 2}, {}, {7}}
+
+	got := make([][]int, 0)
+	next := ""
+	for _, batch := range batches {
+		svc.addAll(batch)
+		values, cur, err := svc.since("n1", next)
+		if err != nil {
+			t.Fatalf("since() error = %v", err)
+		}
+		got = append(got, values)
+		next = cur
+	}
+	if want := [][]int{{1, 2}, {}, {7}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("since() = %v, want %v", got, want)
+	}
+}
+
+func TestReadSinceRejectsMalformedCursor(t *testing.T) {
+	n := testNode("n1", []string{"n1"})
+	svc := createBroadcastSvc(time.Second)
+
+	msg := message("c1", map[string]any{"type": "read_since", "msg_id": 1, "cursor": "%%%"})
+	err := handleReadSince(n, svc)(msg)
+	if code := maelstrom.ErrorCode(err); code != maelstrom.MalformedRequest {
+		t.Errorf("read_since error = %v, want code %d", err, maelstrom.MalformedRequest)
+	}
+}
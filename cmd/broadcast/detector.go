@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const HEARTBEAT_MILL = 500
+const SUSPECT_MILL = 2000
+
+// detector is a heartbeat failure detector: a peer is suspected once
+// nothing was heard from it for longer than timeout. Gossip, its acks and
+// sync replies count as heard, so heartbeats are only needed on idle links.
+type detector struct {
+	timeout   time.Duration
+	mu        sync.Mutex
+	lastHeard map[string]time.Time
+}
+
+func createDetector(timeout time.Duration) *detector {
+	return &detector{
+		timeout:   timeout,
+		lastHeard: make(map[string]time.Time),
+	}
+}
+
+// watch starts tracking peers, giving new ones a full timeout of grace.
+func (d *detector) watch(peers []string) {
+	now := time.Now()
+	d.mu.Lock()
+	for _, p := range peers {
+		if _, ok := d.lastHeard[p]; !ok {
+			d.lastHeard[p] = now
+		}
+	}
+	d.mu.Unlock()
+}
+
+// forget stops tracking peers that are no longer neighbors.
+func (d *detector) forget(peers []string) {
+	d.mu.Lock()
+	for _, p := range peers {
+		delete(d.lastHeard, p)
+	}
+	d.mu.Unlock()
+}
+
+func (d *detector) heard(peer string) {
+	d.mu.Lock()
+	if _, ok := d.lastHeard[peer]; ok {
+		d.lastHeard[peer] = time.Now()
+	}
+	d.mu.Unlock()
+}
+
+// suspected reports whether a watched peer went silent. Unwatched peers
+// are never suspected.
+func (d *detector) suspected(peer string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	last, ok := d.lastHeard[peer]
+	return ok && time.Since(last) > d.timeout
+}
+
+// idle reports whether nothing was heard from a watched peer for longer
+// than after.
+func (d *detector) idle(peer string, after time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	last, ok := d.lastHeard[peer]
+	return ok && time.Since(last) > after
+}
+
+// monitor heartbeats neighbors that were idle for an interval and reroutes
+// pending values around the ones that become suspected.
+func (svc *broadcastSvc) monitor(n *maelstrom.Node, interval time.Duration) {
+	suspected := make(map[string]bool)
+	for range time.Tick(interval) {
+		svc.check(n, interval, suspected)
+	}
+}
+
+// check runs one round of monitor. Heartbeats are RPCs, so a silent
+// neighbor is heard from as soon as it answers, even if it has nothing
+// else to send. suspected carries the verdicts of the previous round.
+func (svc *broadcastSvc) check(n *maelstrom.Node, interval time.Duration, suspected map[string]bool) {
+	for _, nbr := range svc.neighbors() {
+		if svc.fd.idle(nbr, interval) {
+			n.RPC(nbr, map[string]string{"type": "heartbeat"}, func(msg maelstrom.Message) error {
+				svc.fd.heard(msg.Src)
+				return nil
+			})
+		}
+
+		s := svc.fd.suspected(nbr)
+		if s && !suspected[nbr] {
+			svc.reroute(nbr)
+		} else if !s && suspected[nbr] {
+			log.Printf("Node %s recovered, back to normal routing", nbr)
+		}
+		suspected[nbr] = s
+	}
+}
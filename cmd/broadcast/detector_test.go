@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// linked runs two nodes whose output is the other's input.
+func linked(t *testing.T, a, b *maelstrom.Node) {
+	ar, aw := io.Pipe()
+	br, bw := io.Pipe()
+	a.Stdout, b.Stdin = aw, ar
+	b.Stdout, a.Stdin = bw, br
+	go a.Run()
+	go b.Run()
+	t.Cleanup(func() {
+		aw.Close()
+		bw.Close()
+	})
+}
+
+func TestMonitorKeepsIdleNeighborsAlive(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	nodes := []string{"n1", "n2"}
+
+	type peer struct {
+		n         *maelstrom.Node
+		svc       *broadcastSvc
+		suspected map[string]bool
+	}
+	peers := make([]peer, 0)
+	for _, id := range nodes {
+		n := testNode(id, nodes)
+		svc := createBroadcastSvc(5 * interval)
+		svc.register(n)
+		svc.setNeighbors(without(nodes, []string{id}))
+		peers = append(peers, peer{n: n, svc: svc, suspected: make(map[string]bool)})
+	}
+	linked(t, peers[0].n, peers[1].n)
+
+	// nothing but heartbeats for four times the suspicion timeout
+	for i := 0; i < 20; i++ {
+		time.Sleep(interval)
+		for _, p := range peers {
+			p.svc.check(p.n, interval, p.suspected)
+		}
+	}
+
+	for i, p := range peers {
+		nbr := nodes[1-i]
+		if p.suspected[nbr] || p.svc.fd.suspected(nbr) {
+			t.Errorf("%s suspects idle but healthy %s", nodes[i], nbr)
+		}
+	}
+}
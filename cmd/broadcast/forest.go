@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/intervals"
+	"github.com/AxelUser/dist-sys-challenge/internal/rtt"
+	"github.com/AxelUser/dist-sys-challenge/internal/topology"
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const TREES = 2
+
+type treeBody struct {
+	Type     string `json:"type"`
+	Tree     int    `json:"tree"`
+	Messages []int  `json:"messages"`
+}
+
+// forest sends every value along a primary spanning tree and falls back to
+// the next of several edge-disjoint trees for values a neighbor does not
+// acknowledge in time. A node relays a value at most once per tree, so the
+// redundancy is bounded by the number of trees rather than the cluster size.
+// The trees are built from the cluster members, the topology is ignored.
+type forest struct {
+	n       *maelstrom.Node
+	svc     *broadcastSvc
+	k       int
+	mu      sync.Mutex
+	nbrs    [][]string           // own neighbors per tree, primary first
+	relayed []*intervals.Set     // values relayed per tree
+	pending []map[string]*outbox // per tree and neighbor
+	rtts    map[string]*rtt.Estimator
+}
+
+func createForest(n *maelstrom.Node, svc *broadcastSvc, k int) *forest {
+	return &forest{
+		n:       n,
+		svc:     svc,
+		k:       k,
+		nbrs:    make([][]string, 0),
+		relayed: make([]*intervals.Set, 0),
+		pending: make([]map[string]*outbox, 0),
+		rtts:    make(map[string]*rtt.Estimator),
+	}
+}
+
+// setNeighbors rebuilds the trees for the current members. Values still
+// pending for a node that is no longer a neighbor in some tree go to the
+// new neighbors in that tree. Like any topology, every tree must pass
+// topology.Validate; otherwise the previous trees are kept.
+func (f *forest) setNeighbors(_ []string) {
+	members := f.svc.cluster()
+	trees := topology.DisjointTrees(members, f.k)
+	for t, tree := range trees {
+		if err := topology.Validate(tree, members); err != nil {
+			log.Printf("Keeping previous trees, tree %d is invalid: %v", t, err)
+			return
+		}
+	}
+	self := f.n.ID()
+	f.svc.setNeighbors(trees[0][self])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	nbrs := make([][]string, len(trees))
+	pending := make([]map[string]*outbox, len(trees))
+	for t, tree := range trees {
+		nbrs[t] = tree[self]
+		pending[t] = make(map[string]*outbox)
+		for _, nbr := range nbrs[t] {
+			pending[t][nbr] = newOutbox()
+			if t < len(f.pending) {
+				if box, ok := f.pending[t][nbr]; ok {
+					pending[t][nbr] = box
+				}
+			}
+			if _, ok := f.rtts[nbr]; !ok {
+				f.rtts[nbr] = rtt.NewEstimator(RTO_INIT_MILL*time.Millisecond, RTO_MIN_MILL*time.Millisecond, RETRY_MILL*time.Millisecond)
+			}
+		}
+	}
+	for len(f.relayed) < len(trees) {
+		f.relayed = append(f.relayed, intervals.New())
+	}
+
+	for t, boxes := range f.pending {
+		tree := t
+		if tree >= len(trees) {
+			tree = len(trees) - 1
+		}
+		for peer, box := range boxes {
+			if t == tree && contains(nbrs[t], peer) {
+				continue
+			}
+			for _, nbr := range nbrs[tree] {
+				for _, v := range box.all() {
+					pending[tree][nbr].push(v)
+				}
+			}
+		}
+	}
+
+	f.nbrs = nbrs
+	f.pending = pending
+	log.Printf("Forest of %d trees, neighbors %v", len(trees), nbrs)
+}
+
+// relay queues values for the neighbors in tree t other than src, unless
+// they were relayed in that tree already. Must be called with mu held.
+func (f *forest) relay(t int, values []int, src string) {
+	if t >= len(f.nbrs) {
+		return
+	}
+	for _, v := range values {
+		if !f.relayed[t].Add(v) {
+			continue
+		}
+		for _, nbr := range f.nbrs[t] {
+			if nbr != src {
+				f.pending[t][nbr].push(v)
+			}
+		}
+	}
+}
+
+func (f *forest) spread(values []int, src string) {
+	f.mu.Lock()
+	f.relay(0, values, src)
+	f.mu.Unlock()
+}
+
+func (f *forest) broadcast(v int) {
+	if f.svc.add(v) {
+		f.spread([]int{v}, "")
+	}
+}
+
+func (f *forest) read() []int {
+	return f.svc.values()
+}
+
+// flush sends due values in every tree. Values that a neighbor did not
+// acknowledge in time are also relayed along the next tree.
+func (f *forest) flush() {
+	type treeBatch struct {
+		tree       int
+		dst        string
+		values     []int
+		retransmit bool
+	}
+
+	now := time.Now()
+	batches := make([]treeBatch, 0)
+
+	f.mu.Lock()
+	for t, boxes := range f.pending {
+		for peer, box := range boxes {
+			overdue := box.overdue(now)
+			est := f.rtts[peer]
+			values, retransmit := box.batch(now, est.RTO())
+			if retransmit {
+				est.Timeout()
+				f.relay(t+1, overdue, "")
+			}
+			if len(values) > 0 {
+				batches = append(batches, treeBatch{tree: t, dst: peer, values: values, retransmit: retransmit})
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	for _, batch := range batches {
+		batch := batch
+		f.n.RPC(batch.dst, treeBody{Type: "tree_push", Tree: batch.tree, Messages: batch.values}, func(msg maelstrom.Message) error {
+			f.ack(batch.tree, batch.dst, batch.values, now, batch.retransmit)
+			return nil
+		})
+	}
+}
+
+// ack clears an acknowledged batch; boxes dropped by a rebuild are ignored.
+func (f *forest) ack(t int, peer string, values []int, sent time.Time, retransmit bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t >= len(f.pending) {
+		return
+	}
+	if box, ok := f.pending[t][peer]; ok {
+		box.ack(values)
+		if !retransmit {
+			f.rtts[peer].Observe(time.Since(sent))
+		}
+	}
+}
+
+func (f *forest) run(tick time.Duration) {
+	for range time.Tick(tick) {
+		f.flush()
+	}
+}
+
+func (f *forest) register(n *maelstrom.Node) {
+	n.Handle("tree_push", func(msg maelstrom.Message) error {
+		var body treeBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		f.svc.addAll(body.Messages)
+		f.mu.Lock()
+		f.relay(body.Tree, body.Messages, msg.Src)
+		f.mu.Unlock()
+
+		res := make(map[string]any)
+		res["type"] = "tree_push_ok"
+		return n.Reply(msg, res)
+	})
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"encoding/json"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/AxelUser/dist-sys-challenge/internal/env"
+	"github.com/AxelUser/dist-sys-challenge/internal/intervals"
+	"github.com/AxelUser/dist-sys-challenge/internal/rtt"
 	"github.com/AxelUser/dist-sys-challenge/internal/topology"
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
 )
@@ -15,131 +19,480 @@ type broadcastBody struct {
 	Message int    `json:"message"`
 }
 
+type gossipBody struct {
+	Type     string `json:"type"`
+	Messages []int  `json:"messages"`
+}
+
 type broadcastSvc struct {
-	seen    map[int]struct{}
-	pending map[string]map[int]struct{}
-	nbrs    []string
-	msgLock sync.RWMutex
+	seen        *intervals.Set
+	cache       []int // seen values for read, rebuilt after changes
+	arrivals    []int // seen values in the order they arrived
+	boot        string
+	pending     map[string]*outbox
+	rtts        map[string]*rtt.Estimator
+	nbrs        []string
+	members     []string
+	self        string
+	graph       map[string][]string
+	fd          *detector
+	msgLock     sync.RWMutex
+	pendingLock sync.Mutex
 }
 
-func createBroadcastSvc() *broadcastSvc {
+func createBroadcastSvc(suspectAfter time.Duration) *broadcastSvc {
 	return &broadcastSvc{
-		pending: make(map[string]map[int]struct{}),
-		seen:    make(map[int]struct{}),
+		pending: make(map[string]*outbox),
+		rtts:    make(map[string]*rtt.Estimator),
+		seen:    intervals.New(),
+		boot:    strconv.FormatInt(time.Now().UnixNano(), 36),
 		nbrs:    make([]string, 0),
+		members: make([]string, 0),
+		graph:   make(map[string][]string),
+		fd:      createDetector(suspectAfter),
 	}
 }
 
 func (svc *broadcastSvc) add(v int) bool {
 	svc.msgLock.Lock()
-	var seen bool
-	if _, seen = svc.seen[v]; !seen {
-		svc.seen[v] = struct{}{}
+	added := svc.seen.Add(v)
+	if added {
+		svc.cache = nil
+		svc.arrivals = append(svc.arrivals, v)
 	}
 	svc.msgLock.Unlock()
-	return !seen
+	return added
 }
 
-func (svc *broadcastSvc) values() []int {
+// addAll stores values and returns only those that were not seen before.
+func (svc *broadcastSvc) addAll(values []int) []int {
+	fresh := make([]int, 0, len(values))
+	svc.msgLock.Lock()
+	for _, v := range values {
+		if svc.seen.Add(v) {
+			fresh = append(fresh, v)
+		}
+	}
+	if len(fresh) > 0 {
+		svc.cache = nil
+		svc.arrivals = append(svc.arrivals, fresh...)
+	}
+	svc.msgLock.Unlock()
+	return fresh
+}
+
+func (svc *broadcastSvc) has(v int) bool {
 	svc.msgLock.RLock()
-	cp := make([]int, 0)
-	for v := range svc.seen {
-		cp = append(cp, v)
+	defer svc.msgLock.RUnlock()
+	return svc.seen.Contains(v)
+}
+
+// unknown returns the values that were not seen yet.
+func (svc *broadcastSvc) unknown(values []int) []int {
+	diff := make([]int, 0)
+	svc.msgLock.RLock()
+	for _, v := range values {
+		if !svc.seen.Contains(v) {
+			diff = append(diff, v)
+		}
 	}
 	svc.msgLock.RUnlock()
-	return cp
+	return diff
+}
+
+// values returns seen values in ascending order. The slice is shared
+// between reads until the next change and must not be modified.
+func (svc *broadcastSvc) values() []int {
+	svc.msgLock.Lock()
+	defer svc.msgLock.Unlock()
+	if svc.cache == nil {
+		svc.cache = svc.seen.Values()
+	}
+	return svc.cache
 }
 
+// setNeighbors replaces the neighbors. Everything still pending for a
+// dropped neighbor, in flight or not, moves to the neighbors that replaced
+// it, so a topology change does not lose deliveries. Late acks from a
+// dropped neighbor are ignored.
 func (svc *broadcastSvc) setNeighbors(nodes []string) {
+	svc.pendingLock.Lock()
+	old := svc.nbrs
 	svc.nbrs = nodes
+	for _, nbr := range nodes {
+		svc.box(nbr)
+	}
+
+	heirs := without(nodes, old)
+	if len(heirs) == 0 {
+		heirs = nodes
+	}
+	removed := make([]string, 0)
+	for peer := range svc.pending {
+		// boxes of live detours outlast the neighbor that needed them
+		if contains(nodes, peer) || (!contains(old, peer) && contains(svc.members, peer)) {
+			continue
+		}
+		values := svc.pending[peer].all()
+		for _, heir := range heirs {
+			box := svc.box(heir)
+			for _, v := range values {
+				box.push(v)
+			}
+		}
+		delete(svc.pending, peer)
+		delete(svc.rtts, peer)
+		removed = append(removed, peer)
+		log.Printf("Dropped node %s, moved %d pending values to %v", peer, len(values), heirs)
+	}
+	svc.pendingLock.Unlock()
+
+	svc.fd.forget(removed)
+	svc.fd.watch(nodes)
 }
 
-func (svc *broadcastSvc) neighbors() []string {
-	return svc.nbrs
+// setMembers replaces the known cluster members, self included.
+func (svc *broadcastSvc) setMembers(self string, nodes []string) {
+	svc.pendingLock.Lock()
+	svc.self = self
+	svc.members = nodes
+	svc.pendingLock.Unlock()
 }
 
-func send(n *maelstrom.Node, receivers map[string]bool, body broadcastBody, delay time.Duration) {
-	exit := make(chan bool, 1)
+// cluster lists the members in the order they joined, self included.
+func (svc *broadcastSvc) cluster() []string {
+	svc.pendingLock.Lock()
+	defer svc.pendingLock.Unlock()
+	return svc.members
+}
 
-	count := len(receivers)
-	log.Printf("Broadcasting %d to %d receivers", body.Message, count)
-	var wg sync.WaitGroup
-	wg.Add(count)
+// peers lists every other member of the cluster.
+func (svc *broadcastSvc) peers() []string {
+	svc.pendingLock.Lock()
+	defer svc.pendingLock.Unlock()
+	return without(svc.members, []string{svc.self})
+}
+
+// setGraph remembers the whole topology, so the node knows who can reach
+// past a neighbor that stopped responding.
+func (svc *broadcastSvc) setGraph(self string, graph map[string][]string) {
+	svc.pendingLock.Lock()
+	svc.self = self
+	svc.graph = graph
+	svc.pendingLock.Unlock()
+}
 
-	go func() {
-		wg.Wait()
-		exit <- true
-	}()
+// box returns the outbox for peer, creating it on first use.
+// Must be called with pendingLock held.
+func (svc *broadcastSvc) box(peer string) *outbox {
+	box, ok := svc.pending[peer]
+	if !ok {
+		box = newOutbox()
+		svc.pending[peer] = box
+		svc.rtts[peer] = rtt.NewEstimator(RTO_INIT_MILL*time.Millisecond, RTO_MIN_MILL*time.Millisecond, RETRY_MILL*time.Millisecond)
+	}
+	return box
+}
 
-	var sendLock sync.RWMutex
-	broadcast := func() {
-		for dst := range receivers {
-			sendLock.RLock()
-			received := receivers[dst]
-			sendLock.RUnlock()
-			if received {
+// detours lists the peers that can deliver past nbr while it is suspected.
+// Must be called with pendingLock held.
+func (svc *broadcastSvc) detours(nbr string) []string {
+	peers := make([]string, 0)
+	for _, p := range svc.graph[nbr] {
+		if p != svc.self && p != nbr {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// reroute copies everything pending for a suspected neighbor to its detours.
+// The neighbor keeps its own copy, which is delivered once it recovers.
+func (svc *broadcastSvc) reroute(nbr string) {
+	svc.pendingLock.Lock()
+	defer svc.pendingLock.Unlock()
+
+	detours := svc.detours(nbr)
+	if len(detours) == 0 {
+		log.Printf("Suspecting node %s, no detours available", nbr)
+		return
+	}
+
+	values := svc.box(nbr).all()
+	for _, peer := range detours {
+		box := svc.box(peer)
+		for _, v := range values {
+			box.push(v)
+		}
+	}
+	log.Printf("Suspecting node %s, rerouting %d values via %v", nbr, len(values), detours)
+}
+
+func (svc *broadcastSvc) neighbors() []string {
+	svc.pendingLock.Lock()
+	defer svc.pendingLock.Unlock()
+	return svc.nbrs
+}
+
+// spread queues values for every neighbor except the one they came from.
+// Values for a suspected neighbor also go to its detours.
+func (svc *broadcastSvc) spread(values []int, src string) {
+	if len(values) == 0 {
+		return
+	}
+
+	svc.pendingLock.Lock()
+	for _, nbr := range svc.nbrs {
+		if nbr == src {
+			continue
+		}
+		targets := []string{nbr}
+		if svc.fd.suspected(nbr) {
+			targets = append(targets, svc.detours(nbr)...)
+		}
+		for _, peer := range targets {
+			if peer == src {
 				continue
 			}
-
-			log.Printf("Sending %d to node %s", body.Message, dst)
-			n.RPC(dst, body, func(msg maelstrom.Message) error {
-				log.Printf("Acknowledged %d from node %s", body.Message, msg.Src)
-				sendLock.Lock()
-				receivers[msg.Src] = true
-				sendLock.Unlock()
-				wg.Done()
-				return nil
-			})
-		}
-	}
-
-	go func() {
-		broadcast()
-		for {
-			select {
-			case <-exit:
-				log.Printf("Broadcasting %d completed", body.Message)
-				return
-			case <-time.After(delay): // retry after delay
-				log.Printf("Retry broadcasting %d after %v", body.Message, delay)
-				broadcast()
+			box := svc.box(peer)
+			for _, v := range values {
+				box.push(v)
 			}
 		}
-	}()
+	}
+	svc.pendingLock.Unlock()
+}
+
+// ack clears an acknowledged batch. Only batches without retransmitted
+// values give an unambiguous round-trip sample.
+func (svc *broadcastSvc) ack(peer string, values []int, sent time.Time, retransmit bool) {
+	svc.fd.heard(peer)
+	svc.pendingLock.Lock()
+	if box, ok := svc.pending[peer]; ok {
+		box.ack(values)
+		if !retransmit {
+			svc.rtts[peer].Observe(time.Since(sent))
+		}
+	}
+	svc.pendingLock.Unlock()
+}
+
+// flush sends every peer a single gossip message with its due values.
+// Suspected neighbors are held back while their values travel via detours.
+func (svc *broadcastSvc) flush(n *maelstrom.Node) {
+	type gossipBatch struct {
+		values     []int
+		retransmit bool
+	}
 
+	now := time.Now()
+	batches := make(map[string]gossipBatch)
+
+	svc.pendingLock.Lock()
+	for peer, box := range svc.pending {
+		if svc.fd.suspected(peer) && len(svc.detours(peer)) > 0 {
+			continue
+		}
+		est := svc.rtts[peer]
+		values, retransmit := box.batch(now, est.RTO())
+		if retransmit {
+			est.Timeout()
+		}
+		if len(values) > 0 {
+			batches[peer] = gossipBatch{values: values, retransmit: retransmit}
+		}
+	}
+	svc.pendingLock.Unlock()
+
+	for dst, batch := range batches {
+		batch := batch
+		log.Printf("Gossiping %d values to node %s", len(batch.values), dst)
+		n.RPC(dst, gossipBody{Type: "gossip", Messages: batch.values}, func(msg maelstrom.Message) error {
+			log.Printf("Acknowledged %d values from node %s", len(batch.values), msg.Src)
+			svc.ack(msg.Src, batch.values, now, batch.retransmit)
+			return nil
+		})
+	}
 }
 
-const RETRY_MILL = 5000
+func (svc *broadcastSvc) gossip(n *maelstrom.Node, tick time.Duration) {
+	for range time.Tick(tick) {
+		svc.flush(n)
+	}
+}
 
-func getTopology(nodes []string) map[string][]string {
-	return topology.Tree(nodes, 5)
+func (svc *broadcastSvc) broadcast(v int) {
+	if svc.add(v) {
+		svc.spread([]int{v}, "")
+	}
 }
 
-func main() {
-	n := maelstrom.NewNode()
-	svc := createBroadcastSvc()
+func (svc *broadcastSvc) read() []int {
+	return svc.values()
+}
 
-	n.Handle("broadcast", func(msg maelstrom.Message) error {
-		var body broadcastBody
+func (svc *broadcastSvc) register(n *maelstrom.Node) {
+	n.Handle("gossip", func(msg maelstrom.Message) error {
+		var body gossipBody
 		if err := json.Unmarshal(msg.Body, &body); err != nil {
 			return err
 		}
 
-		if svc.add(body.Message) {
-			log.Printf("Received %d from node %s", body.Message, msg.Src)
+		svc.fd.heard(msg.Src)
+		fresh := svc.addAll(body.Messages)
+		log.Printf("Received %d new of %d values from node %s", len(fresh), len(body.Messages), msg.Src)
+		svc.spread(fresh, msg.Src)
 
-			neighbors := svc.neighbors()
+		res := make(map[string]any)
+		res["type"] = "gossip_ok"
+		return n.Reply(msg, res)
+	})
 
-			receivers := make(map[string]bool)
-			for _, nbr := range neighbors {
-				if nbr == msg.Src {
-					continue
-				}
-				receivers[nbr] = false
+	n.Handle("heartbeat", func(msg maelstrom.Message) error {
+		svc.fd.heard(msg.Src)
+		return n.Reply(msg, map[string]string{"type": "heartbeat_ok"})
+	})
+}
+
+const RETRY_MILL = 5000 // upper bound for the retransmission timeout
+const RTO_INIT_MILL = 1000
+const RTO_MIN_MILL = 50
+const GOSSIP_MILL = 200
+const SYNC_MILL = 1000
+
+// broadcaster delivers client values across the cluster.
+type broadcaster interface {
+	setNeighbors(nbrs []string)
+	broadcast(v int)
+	read() []int
+}
+
+// spreader forwards values that are new to this node to its peers.
+type spreader interface {
+	spread(values []int, src string)
+}
+
+func main() {
+	n := maelstrom.NewNode()
+	svc := createBroadcastSvc(env.Millis("BROADCAST_SUSPECT_MILL", SUSPECT_MILL))
+
+	strategy, err := topology.Parse(env.String("BROADCAST_TOPOLOGY", DEFAULT_TOPOLOGY))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Using topology %s", strategy.Spec)
+
+	tick := env.Millis("BROADCAST_GOSSIP_MILL", GOSSIP_MILL)
+	log.Printf("Gossip tick is %v", tick)
+
+	mode := env.String("BROADCAST_MODE", "gossip")
+	var b broadcaster
+	// work that needs the node ID or the cluster membership starts on init
+	onInit := make([]func() error, 0)
+	// durable snapshots seen values, and with pending also the outboxes of
+	// svc, which only gossip mode flushes
+	durable := func(pending bool) {
+		kv := maelstrom.NewSeqKV(n)
+		interval := env.Millis("BROADCAST_SNAPSHOT_MILL", SNAPSHOT_MILL)
+		onInit = append(onInit, func() error {
+			if err := svc.recover(n, kv, pending); err != nil {
+				log.Printf("Failed to restore snapshot: %v", err)
 			}
+			go svc.persist(n, kv, interval, pending)
+			return nil
+		})
+	}
+	syncInterval := env.Millis("BROADCAST_SYNC_MILL", SYNC_MILL)
+	log.Printf("Anti-entropy interval is %v", syncInterval)
+
+	switch mode {
+	case "gossip":
+		svc.register(n)
+		n.Handle("sync", handleSync(n, svc, svc))
+		b = svc
+		go svc.gossip(n, tick)
+		go svc.monitor(n, env.Millis("BROADCAST_HEARTBEAT_MILL", HEARTBEAT_MILL))
+		go svc.antiEntropy(n, svc, svc.neighbors, syncInterval)
+		durable(true)
+	case "plumtree":
+		pt := createPlumtree(n, svc, env.Int("BROADCAST_LAZY_FANOUT", LAZY_FANOUT), env.Millis("BROADCAST_GRAFT_MILL", GRAFT_MILL))
+		pt.register(n)
+		n.Handle("sync", handleSync(n, svc, pt))
+		b = pt
+		go pt.run(tick, env.Millis("BROADCAST_IHAVE_MILL", IHAVE_MILL))
+		go svc.antiEntropy(n, pt, svc.neighbors, syncInterval)
+		durable(false)
+	case "pushpull":
+		pp := createPushPull(n, svc, env.Int("BROADCAST_FANOUT", FANOUT), env.Int("BROADCAST_RECENT_ROUNDS", RECENT_ROUNDS))
+		pp.register(n)
+		n.Handle("sync", handleSync(n, svc, pp))
+		b = pp
+		go pp.run(env.Millis("BROADCAST_ROUND_MILL", ROUND_MILL))
+		go svc.antiEntropy(n, pp, pp.peers, syncInterval)
+		durable(false)
+	case "trees":
+		if spec := env.String("BROADCAST_TOPOLOGY", ""); spec != "" {
+			log.Fatalf("BROADCAST_TOPOLOGY=%s has no effect in trees mode, which builds its own spanning trees", spec)
+		}
+		f := createForest(n, svc, env.Int("BROADCAST_TREES", TREES))
+		f.register(n)
+		n.Handle("sync", handleSync(n, svc, f))
+		b = f
+		go f.run(tick)
+		go svc.antiEntropy(n, f, svc.neighbors, syncInterval)
+		durable(false)
+	case "causal":
+		c := createCausal(n, svc)
+		c.register(n)
+		b = c
+		go c.run(tick, syncInterval)
+	case "total":
+		t := createTotalOrder(n, svc, env.Millis("BROADCAST_ELECTION_MILL", ELECTION_MILL))
+		t.register(n)
+		b = t
+		onInit = append(onInit, func() error {
+			go t.run(tick)
+			return nil
+		})
+	default:
+		log.Fatalf("unknown broadcast mode %q", mode)
+	}
+	log.Printf("Using broadcast mode %s", mode)
 
-			send(n, receivers, body, time.Duration(time.Millisecond*RETRY_MILL))
+	var latency *latencyMatrix
+	if strategy.NeedsLatency {
+		latency = createLatencyMatrix()
+	}
+	m := createMembership(n, svc, b, strategy, latency)
+	m.register(n)
+	onInit = append([]func() error{m.start}, onInit...)
+
+	if latency != nil {
+		p := createProber(n, latency, svc.peers, m.refresh)
+		p.register(n)
+		onInit = append(onInit, func() error {
+			go p.run(PROBE_ROUNDS, time.Millisecond*PROBE_MILL, time.Millisecond*PROBE_TIMEOUT_MILL)
+			return nil
+		})
+	}
+
+	n.Handle("init", func(msg maelstrom.Message) error {
+		for _, fn := range onInit {
+			if err := fn(); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+
+	n.Handle("broadcast", func(msg maelstrom.Message) error {
+		var body broadcastBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		log.Printf("Received %d from node %s", body.Message, msg.Src)
+		b.broadcast(body.Message)
 
 		res := make(map[string]any)
 		res["type"] = "broadcast_ok"
@@ -150,30 +503,12 @@ func main() {
 		body := make(map[string]any)
 
 		body["type"] = "read_ok"
-		body["messages"] = svc.values()
+		body["messages"] = b.read()
 
 		return n.Reply(msg, body)
 	})
 
-	type topologyBody struct {
-		Topology map[string][]string `json:"topology"`
-	}
-
-	n.Handle("topology", func(msg maelstrom.Message) error {
-		var body topologyBody
-		if err := json.Unmarshal(msg.Body, &body); err != nil {
-			return err
-		}
-
-		neighbors := getTopology(n.NodeIDs())[n.ID()]
-		log.Printf("Topology for %s: %v", n.ID(), neighbors)
-		svc.setNeighbors(neighbors)
-
-		res := make(map[string]any)
-		res["type"] = "topology_ok"
-
-		return n.Reply(msg, res)
-	})
+	n.Handle("read_since", handleReadSince(n, svc))
 
 	if err := n.Run(); err != nil {
 		log.Fatal(err)
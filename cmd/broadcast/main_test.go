@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetNeighborsMovesPending(t *testing.T) {
+	members := []string{"n1", "n2", "n3", "n4", "n5"}
+	type args struct {
+		before  []string
+		after   []string
+		pending map[string][]int // pushed before the change, the first value is in flight
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string][]int
+	}{
+		{
+			name: "replaced neighbor hands its values to the replacement",
+			args: args{
+				before: []string{"n2", "n3"},
+				after:  []string{"n3", "n5"},
+				pending: map[string][]int{
+					"n2": {1, 2},
+					"n3": {3},
+				},
+			},
+			want: map[string][]int{
+				"n3": {3},
+				"n5": {1, 2},
+			},
+		},
+		{
+			name: "dropped neighbor without replacement hands its values to all",
+			args: args{
+				before:  []string{"n2", "n3", "n4"},
+				after:   []string{"n3", "n4"},
+				pending: map[string][]int{"n2": {1, 2}},
+			},
+			want: map[string][]int{
+				"n3": {1, 2},
+				"n4": {1, 2},
+			},
+		},
+		{
+			name: "detour to a member outlasts the change",
+			args: args{
+				before: []string{"n2", "n3"},
+				after:  []string{"n2", "n5"},
+				pending: map[string][]int{
+					"n3": {1},
+					"n4": {2},
+				},
+			},
+			want: map[string][]int{
+				"n4": {2},
+				"n5": {1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := createBroadcastSvc(time.Second)
+			svc.setMembers("n1", members)
+			svc.setNeighbors(tt.args.before)
+
+			svc.pendingLock.Lock()
+			for peer, values := range tt.args.pending {
+				box := svc.box(peer)
+				box.push(values[0])
+				box.batch(time.Now(), time.Minute)
+				for _, v := range values[1:] {
+					box.push(v)
+				}
+			}
+			svc.pendingLock.Unlock()
+
+			svc.setNeighbors(tt.args.after)
+			if got := pendingValues(svc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pending = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAckFromDroppedNeighborIsIgnored(t *testing.T) {
+	svc := createBroadcastSvc(time.Second)
+	svc.setMembers("n1", []string{"n1", "n2", "n3", "n4"})
+	svc.setNeighbors([]string{"n2", "n3"})
+
+	svc.pendingLock.Lock()
+	svc.box("n2").push(1)
+	svc.box("n2").batch(time.Now(), time.Minute)
+	svc.pendingLock.Unlock()
+	sent := time.Now()
+
+	svc.setNeighbors([]string{"n3", "n4"})
+	// the batch in flight to n2 is acknowledged after n2 was dropped
+	svc.ack("n2", []int{1}, sent, false)
+
+	want := map[string][]int{"n4": {1}}
+	if got := pendingValues(svc); !reflect.DeepEqual(got, want) {
+		t.Errorf("pending = %v, want %v", got, want)
+	}
+	svc.pendingLock.Lock()
+	_, box := svc.pending["n2"]
+	_, est := svc.rtts["n2"]
+	svc.pendingLock.Unlock()
+	if box || est {
+		t.Errorf("late ack revived state for dropped n2: outbox %v, estimator %v", box, est)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/topology"
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const DEFAULT_TOPOLOGY = "tree:5"
+
+type memberBody struct {
+	Type string `json:"type"`
+	Node string `json:"node"`
+}
+
+type topologyBody struct {
+	Topology map[string][]string `json:"topology"`
+}
+
+// membership tracks the nodes in the cluster and rewires the broadcaster
+// every time they or the suggested topology change. Changes are applied
+// one at a time, so neighbors always match the latest membership.
+// Every node must see the same changes to build the same topology.
+type membership struct {
+	n        *maelstrom.Node
+	svc      *broadcastSvc
+	b        broadcaster
+	strategy *topology.Strategy
+	latency  *latencyMatrix // only for strategies built from round trips
+	mu       sync.Mutex
+	members  []string
+	given    map[string][]string
+	built    bool // whether the first topology message arrived
+}
+
+func createMembership(n *maelstrom.Node, svc *broadcastSvc, b broadcaster, strategy *topology.Strategy, latency *latencyMatrix) *membership {
+	return &membership{
+		n:        n,
+		svc:      svc,
+		b:        b,
+		strategy: strategy,
+		latency:  latency,
+		members:  make([]string, 0),
+		given:    make(map[string][]string),
+	}
+}
+
+// start takes the initial members from init.
+func (m *membership) start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members = append(m.members, m.n.NodeIDs()...)
+	m.svc.setMembers(m.n.ID(), m.members)
+	return nil
+}
+
+// rebuild builds the topology for the current members and applies it. An
+// invalid topology is logged and the previous neighbors are kept.
+// Must be called with mu held.
+func (m *membership) rebuild() {
+	in := topology.Input{Given: restrict(m.given, m.members)}
+	if m.latency != nil {
+		in.Latency = m.latency.snapshot()
+	}
+	graph, err := m.strategy.Build(m.members, in)
+	if err != nil {
+		log.Printf("Keeping previous neighbors: %v", err)
+		return
+	}
+	neighbors := graph[m.n.ID()]
+	log.Printf("Topology %s for %s among %d members: %v", m.strategy.Spec, m.n.ID(), len(m.members), neighbors)
+	log.Printf("Topology metrics: %+v", topology.Analyze(graph))
+	m.svc.setMembers(m.n.ID(), m.members)
+	m.svc.setGraph(m.n.ID(), graph)
+	m.b.setNeighbors(neighbors)
+}
+
+func (m *membership) setTopology(given map[string][]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.given = given
+	m.built = true
+	m.rebuild()
+}
+
+// refresh rebuilds the topology after inputs of the strategy changed.
+func (m *membership) refresh() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.built {
+		m.rebuild()
+	}
+}
+
+func (m *membership) join(node string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if contains(m.members, node) {
+		return
+	}
+	members := make([]string, 0, len(m.members)+1)
+	m.members = append(append(members, m.members...), node)
+	log.Printf("Node %s joined", node)
+	m.rebuild()
+}
+
+func (m *membership) leave(node string) error {
+	if node == m.n.ID() {
+		return fmt.Errorf("node %s cannot remove itself", node)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !contains(m.members, node) {
+		return nil
+	}
+	m.members = without(m.members, []string{node})
+	log.Printf("Node %s left", node)
+	m.rebuild()
+	return nil
+}
+
+func (m *membership) register(n *maelstrom.Node) {
+	n.Handle("topology", func(msg maelstrom.Message) error {
+		var body topologyBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		m.setTopology(body.Topology)
+
+		res := make(map[string]any)
+		res["type"] = "topology_ok"
+		return n.Reply(msg, res)
+	})
+
+	n.Handle("join", func(msg maelstrom.Message) error {
+		var body memberBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		m.join(body.Node)
+
+		res := make(map[string]any)
+		res["type"] = "join_ok"
+		return n.Reply(msg, res)
+	})
+
+	n.Handle("leave", func(msg maelstrom.Message) error {
+		var body memberBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		if err := m.leave(body.Node); err != nil {
+			return maelstrom.NewRPCError(maelstrom.PreconditionFailed, err.Error())
+		}
+
+		res := make(map[string]any)
+		res["type"] = "leave_ok"
+		return n.Reply(msg, res)
+	})
+}
+
+// restrict drops nodes that are not members from a suggested topology.
+func restrict(given map[string][]string, members []string) map[string][]string {
+	graph := make(map[string][]string)
+	for node, nbrs := range given {
+		if contains(members, node) {
+			kept := make([]string, 0, len(nbrs))
+			for _, nbr := range nbrs {
+				if contains(members, nbr) {
+					kept = append(kept, nbr)
+				}
+			}
+			graph[node] = kept
+		}
+	}
+	return graph
+}
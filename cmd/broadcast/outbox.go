@@ -0,0 +1,83 @@
+package main
+
+import "time"
+
+// outbox collects values waiting to be delivered to a single neighbor.
+// Values stay in the outbox until the neighbor acknowledges the batch
+// that carried them, so a lost batch is simply resent on a later flush.
+type outbox struct {
+	queued   map[int]struct{}
+	inflight map[int]time.Time // value -> deadline after which it is resent
+}
+
+func newOutbox() *outbox {
+	return &outbox{
+		queued:   make(map[int]struct{}),
+		inflight: make(map[int]time.Time),
+	}
+}
+
+func (o *outbox) push(v int) {
+	if _, ok := o.inflight[v]; ok {
+		return
+	}
+	o.queued[v] = struct{}{}
+}
+
+// batch drains queued values together with in-flight values whose deadline
+// has passed and marks all of them as in-flight until now+retry. It also
+// reports whether the batch retransmits anything.
+func (o *outbox) batch(now time.Time, retry time.Duration) ([]int, bool) {
+	values := make([]int, 0, len(o.queued))
+	for v := range o.queued {
+		values = append(values, v)
+	}
+	retransmit := false
+	for v, deadline := range o.inflight {
+		if now.After(deadline) {
+			values = append(values, v)
+			retransmit = true
+		}
+	}
+
+	deadline := now.Add(retry)
+	for _, v := range values {
+		delete(o.queued, v)
+		o.inflight[v] = deadline
+	}
+
+	return values, retransmit
+}
+
+// overdue returns the in-flight values whose deadline has passed.
+func (o *outbox) overdue(now time.Time) []int {
+	values := make([]int, 0)
+	for v, deadline := range o.inflight {
+		if now.After(deadline) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func (o *outbox) ack(values []int) {
+	for _, v := range values {
+		delete(o.inflight, v)
+	}
+}
+
+func (o *outbox) size() int {
+	return len(o.queued) + len(o.inflight)
+}
+
+// all returns every value that is queued or in-flight.
+func (o *outbox) all() []int {
+	values := make([]int, 0, o.size())
+	for v := range o.queued {
+		values = append(values, v)
+	}
+	for v := range o.inflight {
+		values = append(values, v)
+	}
+	return values
+}
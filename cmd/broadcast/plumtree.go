@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const LAZY_FANOUT = 3
+const GRAFT_MILL = 1000
+const IHAVE_MILL = 500
+
+// missingValue tracks a value announced by lazy peers but not yet delivered.
+type missingValue struct {
+	announcers []string
+	deadline   time.Time
+}
+
+// plumtree implements epidemic broadcast trees: values are pushed eagerly
+// along a spanning tree and announced lazily to a few other peers. A peer
+// that keeps announcing values we never got is grafted into the tree, and
+// an eager peer that only delivers duplicates is pruned out of it.
+type plumtree struct {
+	n        *maelstrom.Node
+	svc      *broadcastSvc
+	fanout   int
+	graft    time.Duration
+	mu       sync.Mutex
+	eager    map[string]bool
+	lazy     map[string]bool
+	push     map[string][]int
+	announce map[string][]int
+	missing  map[int]*missingValue
+}
+
+func createPlumtree(n *maelstrom.Node, svc *broadcastSvc, fanout int, graft time.Duration) *plumtree {
+	return &plumtree{
+		n:        n,
+		svc:      svc,
+		fanout:   fanout,
+		graft:    graft,
+		eager:    make(map[string]bool),
+		lazy:     make(map[string]bool),
+		push:     make(map[string][]int),
+		announce: make(map[string][]int),
+		missing:  make(map[int]*missingValue),
+	}
+}
+
+// setNeighbors makes the tree neighbors eager and picks random lazy peers
+// among the remaining nodes.
+func (pt *plumtree) setNeighbors(nbrs []string) {
+	pt.svc.setNeighbors(nbrs)
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.eager = make(map[string]bool)
+	for _, nbr := range nbrs {
+		pt.eager[nbr] = true
+	}
+
+	others := make([]string, 0)
+	for _, node := range pt.svc.peers() {
+		if !pt.eager[node] {
+			others = append(others, node)
+		}
+	}
+	rand.Shuffle(len(others), func(i, j int) { others[i], others[j] = others[j], others[i] })
+	if len(others) > pt.fanout {
+		others = others[:pt.fanout]
+	}
+
+	pt.lazy = make(map[string]bool)
+	for _, node := range others {
+		pt.lazy[node] = true
+	}
+	log.Printf("Plumtree eager peers %v, lazy peers %v", nbrs, others)
+}
+
+func (pt *plumtree) spread(values []int, src string) {
+	if len(values) == 0 {
+		return
+	}
+
+	pt.mu.Lock()
+	for _, v := range values {
+		delete(pt.missing, v)
+	}
+	for peer := range pt.eager {
+		if peer != src {
+			pt.push[peer] = append(pt.push[peer], values...)
+		}
+	}
+	for peer := range pt.lazy {
+		if peer != src {
+			pt.announce[peer] = append(pt.announce[peer], values...)
+		}
+	}
+	pt.mu.Unlock()
+}
+
+func (pt *plumtree) broadcast(v int) {
+	if pt.svc.add(v) {
+		pt.spread([]int{v}, "")
+	}
+}
+
+func (pt *plumtree) read() []int {
+	return pt.svc.values()
+}
+
+// makeEager moves peer into the tree.
+func (pt *plumtree) makeEager(peer string) {
+	if !pt.eager[peer] {
+		log.Printf("Grafting node %s into the tree", peer)
+	}
+	delete(pt.lazy, peer)
+	pt.eager[peer] = true
+}
+
+// makeLazy moves peer out of the tree.
+func (pt *plumtree) makeLazy(peer string) {
+	if pt.eager[peer] {
+		log.Printf("Pruning node %s from the tree", peer)
+	}
+	delete(pt.eager, peer)
+	pt.lazy[peer] = true
+}
+
+// flush sends queued pushes, then grafts peers that announced values which
+// did not arrive in time.
+func (pt *plumtree) flush() {
+	now := time.Now()
+	grafts := make(map[string][]int)
+
+	pt.mu.Lock()
+	push := pt.push
+	pt.push = make(map[string][]int)
+
+	for v, m := range pt.missing {
+		if now.Before(m.deadline) {
+			continue
+		}
+		// ask announcers in turn, so a dead one doesn't stall recovery
+		peer := m.announcers[0]
+		m.announcers = append(m.announcers[1:], peer)
+		m.deadline = now.Add(pt.graft)
+		pt.makeEager(peer)
+		grafts[peer] = append(grafts[peer], v)
+	}
+	pt.mu.Unlock()
+
+	for dst, values := range push {
+		pt.n.Send(dst, gossipBody{Type: "pt_push", Messages: values})
+	}
+	for dst, values := range grafts {
+		log.Printf("Grafting %d missing values from node %s", len(values), dst)
+		pt.n.Send(dst, gossipBody{Type: "pt_graft", Messages: values})
+	}
+}
+
+// announceAll sends queued announcements. They are only needed for
+// recovery, so they go out less often than pushes to keep overhead low.
+func (pt *plumtree) announceAll() {
+	pt.mu.Lock()
+	announce := pt.announce
+	pt.announce = make(map[string][]int)
+	pt.mu.Unlock()
+
+	for dst, values := range announce {
+		pt.n.Send(dst, gossipBody{Type: "pt_ihave", Messages: values})
+	}
+}
+
+func (pt *plumtree) run(tick time.Duration, lazyTick time.Duration) {
+	go func() {
+		for range time.Tick(lazyTick) {
+			pt.announceAll()
+		}
+	}()
+	for range time.Tick(tick) {
+		pt.flush()
+	}
+}
+
+func (pt *plumtree) handlePush(msg maelstrom.Message) error {
+	var body gossipBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	fresh := pt.svc.addAll(body.Messages)
+
+	pt.mu.Lock()
+	prune := len(fresh) == 0 && len(body.Messages) > 0
+	if prune {
+		pt.makeLazy(msg.Src)
+	} else {
+		pt.makeEager(msg.Src)
+	}
+	pt.mu.Unlock()
+
+	if prune {
+		return pt.n.Send(msg.Src, gossipBody{Type: "pt_prune"})
+	}
+	pt.spread(fresh, msg.Src)
+	return nil
+}
+
+func (pt *plumtree) handleIHave(msg maelstrom.Message) error {
+	var body gossipBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	unknown := pt.svc.unknown(body.Messages)
+
+	pt.mu.Lock()
+	deadline := time.Now().Add(pt.graft)
+	for _, v := range unknown {
+		if m, ok := pt.missing[v]; ok {
+			if !contains(m.announcers, msg.Src) {
+				m.announcers = append(m.announcers, msg.Src)
+			}
+		} else {
+			pt.missing[v] = &missingValue{announcers: []string{msg.Src}, deadline: deadline}
+		}
+	}
+	pt.mu.Unlock()
+	return nil
+}
+
+func (pt *plumtree) handleGraft(msg maelstrom.Message) error {
+	var body gossipBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	pt.mu.Lock()
+	pt.makeEager(msg.Src)
+	pt.mu.Unlock()
+
+	return pt.n.Send(msg.Src, gossipBody{Type: "pt_push", Messages: body.Messages})
+}
+
+func (pt *plumtree) handlePrune(msg maelstrom.Message) error {
+	pt.mu.Lock()
+	pt.makeLazy(msg.Src)
+	pt.mu.Unlock()
+	return nil
+}
+
+func (pt *plumtree) register(n *maelstrom.Node) {
+	n.Handle("pt_push", pt.handlePush)
+	n.Handle("pt_ihave", pt.handleIHave)
+	n.Handle("pt_graft", pt.handleGraft)
+	n.Handle("pt_prune", pt.handlePrune)
+}
+
+func contains(nodes []string, node string) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns the nodes that are not in excluded.
+func without(nodes []string, excluded []string) []string {
+	rest := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if !contains(excluded, node) {
+			rest = append(rest, node)
+		}
+	}
+	return rest
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const PROBE_ROUNDS = 3
+const PROBE_MILL = 100
+const PROBE_TIMEOUT_MILL = 1000
+
+type latencyBody struct {
+	Type string                   `json:"type"`
+	Node string                   `json:"node"`
+	Row  map[string]time.Duration `json:"row"`
+}
+
+// latencyMatrix holds the round trips every node measured to its peers.
+// Nodes share their rows, so all of them build the same weighted topology
+// once every row arrived.
+type latencyMatrix struct {
+	mu   sync.Mutex
+	rows map[string]map[string]time.Duration
+}
+
+func createLatencyMatrix() *latencyMatrix {
+	return &latencyMatrix{rows: make(map[string]map[string]time.Duration)}
+}
+
+func (lm *latencyMatrix) set(node string, row map[string]time.Duration) {
+	lm.mu.Lock()
+	lm.rows[node] = row
+	lm.mu.Unlock()
+}
+
+func (lm *latencyMatrix) snapshot() map[string]map[string]time.Duration {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	rows := make(map[string]map[string]time.Duration, len(lm.rows))
+	for node, row := range lm.rows {
+		rows[node] = row
+	}
+	return rows
+}
+
+// prober measures round trips to every peer with ping/pong after init and
+// shares the result with the cluster. changed is called whenever a row is
+// learned, so the topology can be rebuilt.
+type prober struct {
+	n       *maelstrom.Node
+	matrix  *latencyMatrix
+	peers   func() []string
+	changed func()
+}
+
+func createProber(n *maelstrom.Node, matrix *latencyMatrix, peers func() []string, changed func()) *prober {
+	return &prober{n: n, matrix: matrix, peers: peers, changed: changed}
+}
+
+// probe pings every peer a few times and keeps the fastest round trip,
+// which is the one least disturbed by queueing.
+func (p *prober) probe(rounds int, interval time.Duration, timeout time.Duration) map[string]time.Duration {
+	var mu sync.Mutex
+	row := make(map[string]time.Duration)
+	for i := 0; i < rounds; i++ {
+		sent := time.Now()
+		for _, peer := range p.peers() {
+			peer := peer
+			p.n.RPC(peer, map[string]string{"type": "ping"}, func(msg maelstrom.Message) error {
+				rtt := time.Since(sent)
+				mu.Lock()
+				if best, ok := row[peer]; !ok || rtt < best {
+					row[peer] = rtt
+				}
+				mu.Unlock()
+				return nil
+			})
+		}
+		time.Sleep(interval)
+	}
+	time.Sleep(timeout)
+
+	mu.Lock()
+	defer mu.Unlock()
+	measured := make(map[string]time.Duration, len(row))
+	for peer, rtt := range row {
+		measured[peer] = rtt
+	}
+	return measured
+}
+
+// share sends the row to a peer until it is acknowledged.
+func (p *prober) share(peer string, body latencyBody, timeout time.Duration) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_, err := p.n.SyncRPC(ctx, peer, body)
+		cancel()
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to share latencies with node %s: %v", peer, err)
+	}
+}
+
+func (p *prober) run(rounds int, interval time.Duration, timeout time.Duration) {
+	row := p.probe(rounds, interval, timeout)
+	log.Printf("Measured round trips: %v", row)
+
+	p.matrix.set(p.n.ID(), row)
+	p.changed()
+
+	body := latencyBody{Type: "latency", Node: p.n.ID(), Row: row}
+	for _, peer := range p.peers() {
+		go p.share(peer, body, timeout)
+	}
+}
+
+func (p *prober) register(n *maelstrom.Node) {
+	n.Handle("ping", func(msg maelstrom.Message) error {
+		return n.Reply(msg, map[string]string{"type": "pong"})
+	})
+
+	n.Handle("latency", func(msg maelstrom.Message) error {
+		var body latencyBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		p.matrix.set(body.Node, body.Row)
+		p.changed()
+
+		return n.Reply(msg, map[string]string{"type": "latency_ok"})
+	})
+}
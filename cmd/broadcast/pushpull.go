@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const FANOUT = 3
+const ROUND_MILL = 200
+const RECENT_ROUNDS = 8
+
+// pushPull ignores the topology: every round it picks random peers and
+// swaps recently learned values with them in both directions. A value stays
+// recent for a fixed number of rounds, after which anti-entropy is the only
+// way it still travels.
+type pushPull struct {
+	n      *maelstrom.Node
+	svc    *broadcastSvc
+	fanout int
+	ttl    int
+	mu     sync.Mutex
+	recent map[int]int // value -> rounds it stays recent
+}
+
+func createPushPull(n *maelstrom.Node, svc *broadcastSvc, fanout int, ttl int) *pushPull {
+	return &pushPull{
+		n:      n,
+		svc:    svc,
+		fanout: fanout,
+		ttl:    ttl,
+		recent: make(map[int]int),
+	}
+}
+
+func (pp *pushPull) setNeighbors(nbrs []string) {
+	log.Printf("Push-pull gossip picks random peers, ignoring neighbors %v", nbrs)
+}
+
+func (pp *pushPull) spread(values []int, src string) {
+	pp.mu.Lock()
+	for _, v := range values {
+		pp.recent[v] = pp.ttl
+	}
+	pp.mu.Unlock()
+}
+
+func (pp *pushPull) broadcast(v int) {
+	if pp.svc.add(v) {
+		pp.spread([]int{v}, "")
+	}
+}
+
+func (pp *pushPull) read() []int {
+	return pp.svc.values()
+}
+
+// peers lists every other member of the cluster.
+func (pp *pushPull) peers() []string {
+	return pp.svc.peers()
+}
+
+// recentValues returns the recent values and ages them by one round.
+func (pp *pushPull) recentValues() []int {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+
+	values := make([]int, 0, len(pp.recent))
+	for v, rounds := range pp.recent {
+		values = append(values, v)
+		if rounds <= 1 {
+			delete(pp.recent, v)
+		} else {
+			pp.recent[v] = rounds - 1
+		}
+	}
+	return values
+}
+
+// round exchanges recent values with fanout random peers. Rounds without
+// recent values are skipped: peers that learned something push it to us.
+func (pp *pushPull) round() {
+	values := pp.recentValues()
+	if len(values) == 0 {
+		return
+	}
+
+	peers := pp.peers()
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > pp.fanout {
+		peers = peers[:pp.fanout]
+	}
+
+	for _, dst := range peers {
+		pp.n.RPC(dst, gossipBody{Type: "pushpull", Messages: values}, func(msg maelstrom.Message) error {
+			var body gossipBody
+			if err := json.Unmarshal(msg.Body, &body); err != nil {
+				return err
+			}
+			fresh := pp.svc.addAll(body.Messages)
+			if len(fresh) > 0 {
+				log.Printf("Pulled %d values from node %s", len(fresh), msg.Src)
+			}
+			pp.spread(fresh, msg.Src)
+			return nil
+		})
+	}
+}
+
+func (pp *pushPull) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		pp.round()
+	}
+}
+
+func (pp *pushPull) register(n *maelstrom.Node) {
+	n.Handle("pushpull", func(msg maelstrom.Message) error {
+		var body gossipBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+
+		pushed := make(map[int]bool, len(body.Messages))
+		for _, v := range body.Messages {
+			pushed[v] = true
+		}
+		pp.mu.Lock()
+		reply := make([]int, 0)
+		for v := range pp.recent {
+			if !pushed[v] {
+				reply = append(reply, v)
+			}
+		}
+		pp.mu.Unlock()
+
+		fresh := pp.svc.addAll(body.Messages)
+		pp.spread(fresh, msg.Src)
+
+		return n.Reply(msg, gossipBody{Type: "pushpull_ok", Messages: reply})
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/intervals"
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const SNAPSHOT_MILL = 1000
+const SNAPSHOT_TIMEOUT_MILL = 1000
+
+// snapshot is the part of broadcastSvc that survives a process restart.
+// Pending values are only kept in gossip mode, where the outboxes of
+// broadcastSvc are the ones that get flushed; other modes rely on
+// anti-entropy to spread the restored values again.
+type snapshot struct {
+	Seen    []intervals.Range `json:"seen"`
+	Pending map[string][]int  `json:"pending"`
+}
+
+func snapshotKey(node string) string {
+	return "broadcast-snapshot-" + node
+}
+
+func (svc *broadcastSvc) snapshot(pending bool) snapshot {
+	s := snapshot{
+		Seen:    svc.digest(),
+		Pending: make(map[string][]int),
+	}
+	if !pending {
+		return s
+	}
+
+	svc.pendingLock.Lock()
+	for peer, box := range svc.pending {
+		if box.size() > 0 {
+			s.Pending[peer] = box.all()
+		}
+	}
+	svc.pendingLock.Unlock()
+
+	return s
+}
+
+// restore merges a snapshot into the current state. With pending, pending
+// values are queued again, so they are delivered on the next flush.
+func (svc *broadcastSvc) restore(s snapshot, pending bool) {
+	svc.addAll(intervals.FromRanges(s.Seen).Values())
+	if !pending {
+		return
+	}
+
+	svc.pendingLock.Lock()
+	for peer, values := range s.Pending {
+		box := svc.box(peer)
+		for _, v := range values {
+			box.push(v)
+		}
+	}
+	svc.pendingLock.Unlock()
+}
+
+// recover loads the last snapshot of this node, if there is one.
+func (svc *broadcastSvc) recover(n *maelstrom.Node, kv *maelstrom.KV, pending bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*SNAPSHOT_TIMEOUT_MILL)
+	defer cancel()
+
+	var s snapshot
+	if err := kv.ReadInto(ctx, snapshotKey(n.ID()), &s); err != nil {
+		if rpcErr, ok := err.(*maelstrom.RPCError); ok && rpcErr.Code == maelstrom.KeyDoesNotExist {
+			log.Printf("No snapshot for %s, starting fresh", n.ID())
+			return nil
+		}
+		return err
+	}
+
+	svc.restore(s, pending)
+	log.Printf("Restored %d values from snapshot", intervals.FromRanges(s.Seen).Len())
+	if pending {
+		log.Printf("Restored pending values for %d peers from snapshot", len(s.Pending))
+	}
+	return nil
+}
+
+// persist periodically writes a snapshot whenever the state changed.
+func (svc *broadcastSvc) persist(n *maelstrom.Node, kv *maelstrom.KV, interval time.Duration, pending bool) {
+	var last []byte
+	for range time.Tick(interval) {
+		buf, err := json.Marshal(svc.snapshot(pending))
+		if err != nil {
+			log.Printf("Failed to encode snapshot: %v", err)
+			continue
+		}
+		if bytes.Equal(buf, last) {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*SNAPSHOT_TIMEOUT_MILL)
+		err = kv.Write(ctx, snapshotKey(n.ID()), json.RawMessage(buf))
+		cancel()
+		if err != nil {
+			log.Printf("Failed to write snapshot: %v", err)
+			continue
+		}
+		last = buf
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// pendingValues lists the values in every non-empty outbox, sorted.
+func pendingValues(svc *broadcastSvc) map[string][]int {
+	svc.pendingLock.Lock()
+	defer svc.pendingLock.Unlock()
+	pending := make(map[string][]int)
+	for peer, box := range svc.pending {
+		if values := box.all(); len(values) > 0 {
+			sort.Ints(values)
+			pending[peer] = values
+		}
+	}
+	return pending
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	type args struct {
+		pending bool
+	}
+	type want struct {
+		values  []int
+		pending map[string][]int
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "gossip keeps pending values",
+			args: args{pending: true},
+			want: want{
+				values:  []int{1, 2, 3, 7},
+				pending: map[string][]int{"n2": {3, 7}, "n3": {7}},
+			},
+		},
+		{
+			name: "other modes keep seen values only",
+			args: args{pending: false},
+			want: want{
+				values:  []int{1, 2, 3, 7},
+				pending: map[string][]int{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := createBroadcastSvc(time.Second)
+			svc.addAll([]int{1, 2, 3, 7})
+			svc.pendingLock.Lock()
+			svc.box("n2").push(3)
+			svc.box("n2").push(7)
+			svc.box("n3").push(7)
+			// in-flight values are pending too
+			svc.box("n3").batch(time.Now(), time.Minute)
+			svc.pendingLock.Unlock()
+
+			buf, err := json.Marshal(svc.snapshot(tt.args.pending))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s snapshot
+			if err := json.Unmarshal(buf, &s); err != nil {
+				t.Fatal(err)
+			}
+			restored := createBroadcastSvc(time.Second)
+			restored.restore(s, tt.args.pending)
+
+			got := want{values: restored.values(), pending: pendingValues(restored)}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restore(snapshot()) = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const ELECTION_MILL = 1000
+const MAX_APPEND = 1000
+
+type orderEntry struct {
+	Term  int  `json:"term"`
+	Value int  `json:"value"`
+	Noop  bool `json:"noop,omitempty"`
+}
+
+type submitBody struct {
+	Type     string `json:"type"`
+	Messages []int  `json:"messages"`
+}
+
+type voteBody struct {
+	Type      string `json:"type"`
+	Term      int    `json:"term"`
+	LastIndex int    `json:"last_index"`
+	LastTerm  int    `json:"last_term"`
+}
+
+type voteResBody struct {
+	Type    string `json:"type"`
+	Term    int    `json:"term"`
+	Granted bool   `json:"granted"`
+}
+
+type appendBody struct {
+	Type     string       `json:"type"`
+	Term     int          `json:"term"`
+	Start    int          `json:"start"`
+	PrevTerm int          `json:"prev_term"`
+	Entries  []orderEntry `json:"entries"`
+	Commit   int          `json:"commit"`
+}
+
+type appendResBody struct {
+	Type  string `json:"type"`
+	Term  int    `json:"term"`
+	Ok    bool   `json:"ok"`
+	Match int    `json:"match"`
+}
+
+// totalOrder gives every node the same read order. A single sequencer
+// appends submitted values to a replicated log, and a log entry becomes
+// visible once a majority stored it. When the sequencer goes silent the
+// nodes elect a new one, Raft style: only a node whose log holds every
+// visible entry can win a majority of votes.
+type totalOrder struct {
+	n        *maelstrom.Node
+	svc      *broadcastSvc
+	timeout  time.Duration
+	mu       sync.Mutex
+	term     int
+	votedFor string
+	leader   string
+	votes    map[string]bool
+	deadline time.Time
+	log      []orderEntry
+	logged   map[int]int // value -> number of log entries holding it
+	commit   int
+	next     map[string]int
+	match    map[string]int
+	ordered  []int
+	unsent   map[int]bool // client values not yet in the visible log
+}
+
+func createTotalOrder(n *maelstrom.Node, svc *broadcastSvc, timeout time.Duration) *totalOrder {
+	t := &totalOrder{
+		n:       n,
+		svc:     svc,
+		timeout: timeout,
+		votes:   make(map[string]bool),
+		log:     make([]orderEntry, 0),
+		logged:  make(map[int]int),
+		next:    make(map[string]int),
+		match:   make(map[string]int),
+		ordered: make([]int, 0),
+		unsent:  make(map[int]bool),
+	}
+	t.resetDeadline()
+	return t
+}
+
+func (t *totalOrder) setNeighbors(nbrs []string) {
+	t.svc.setNeighbors(nbrs)
+}
+
+// broadcast keeps the value until it shows up in the visible log, so it is
+// resubmitted to whoever is the sequencer at the time.
+func (t *totalOrder) broadcast(v int) {
+	t.mu.Lock()
+	if !t.svc.has(v) {
+		t.unsent[v] = true
+	}
+	t.mu.Unlock()
+}
+
+func (t *totalOrder) read() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cp := make([]int, len(t.ordered))
+	copy(cp, t.ordered)
+	return cp
+}
+
+// resetDeadline picks a randomized election timeout. Must be called with mu held.
+func (t *totalOrder) resetDeadline() {
+	jitter := time.Duration(rand.Int63n(int64(t.timeout)))
+	t.deadline = time.Now().Add(t.timeout + jitter)
+}
+
+func (t *totalOrder) majority() int {
+	return len(t.n.NodeIDs())/2 + 1
+}
+
+func (t *totalOrder) lastTerm() int {
+	if len(t.log) == 0 {
+		return 0
+	}
+	return t.log[len(t.log)-1].Term
+}
+
+// stepDown adopts a newer term as a follower. Must be called with mu held.
+func (t *totalOrder) stepDown(term int) {
+	if term > t.term {
+		t.term = term
+		t.votedFor = ""
+	}
+	if t.leader == t.n.ID() {
+		log.Printf("Stepping down as sequencer in term %d", t.term)
+	}
+	t.leader = ""
+}
+
+// appendLocal adds values to the log of the sequencer. Must be called with mu held.
+func (t *totalOrder) appendLocal(values []int) {
+	for _, v := range values {
+		if t.logged[v] > 0 || t.svc.has(v) {
+			continue
+		}
+		t.log = append(t.log, orderEntry{Term: t.term, Value: v})
+		t.logged[v]++
+	}
+	t.match[t.n.ID()] = len(t.log)
+}
+
+// truncate drops log entries from idx on. Must be called with mu held.
+func (t *totalOrder) truncate(idx int) {
+	for _, e := range t.log[idx:] {
+		if !e.Noop {
+			t.logged[e.Value]--
+		}
+	}
+	t.log = t.log[:idx]
+}
+
+// apply exposes newly visible log entries to reads. Must be called with mu held.
+func (t *totalOrder) apply(commit int) {
+	if commit > len(t.log) {
+		commit = len(t.log)
+	}
+	for ; t.commit < commit; t.commit++ {
+		if t.log[t.commit].Noop {
+			continue
+		}
+		v := t.log[t.commit].Value
+		delete(t.unsent, v)
+		if t.svc.add(v) {
+			t.ordered = append(t.ordered, v)
+		}
+	}
+}
+
+// advance moves the commit index to the highest entry of the current term
+// that a majority stored. Must be called with mu held.
+func (t *totalOrder) advance() {
+	for idx := len(t.log); idx > t.commit; idx-- {
+		if t.log[idx-1].Term != t.term {
+			break
+		}
+		count := 0
+		for _, node := range t.n.NodeIDs() {
+			if t.match[node] >= idx {
+				count++
+			}
+		}
+		if count >= t.majority() {
+			t.apply(idx)
+			return
+		}
+	}
+}
+
+// tick sends appends as the sequencer, starts an election when the
+// sequencer went silent and resubmits values that are not visible yet.
+func (t *totalOrder) tick() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	self := t.n.ID()
+	if t.leader == self {
+		t.appendLocal(keys(t.unsent))
+		for _, node := range t.n.NodeIDs() {
+			if node != self {
+				t.sendAppend(node)
+			}
+		}
+		t.advance()
+		return
+	}
+
+	if time.Now().After(t.deadline) {
+		t.term++
+		t.votedFor = self
+		t.leader = ""
+		t.votes = map[string]bool{self: true}
+		t.resetDeadline()
+		log.Printf("Sequencer unreachable, starting election for term %d", t.term)
+
+		req := voteBody{Type: "to_vote", Term: t.term, LastIndex: len(t.log), LastTerm: t.lastTerm()}
+		for _, node := range t.n.NodeIDs() {
+			if node != self {
+				t.n.RPC(node, req, t.handleVoteRes)
+			}
+		}
+		t.winIfMajority()
+		return
+	}
+
+	if t.leader != "" && len(t.unsent) > 0 {
+		t.n.Send(t.leader, submitBody{Type: "to_submit", Messages: keys(t.unsent)})
+	}
+}
+
+// winIfMajority turns a candidate with enough votes into the sequencer.
+// Must be called with mu held.
+func (t *totalOrder) winIfMajority() {
+	if t.votedFor != t.n.ID() || t.leader != "" || len(t.votes) < t.majority() {
+		return
+	}
+	log.Printf("Elected sequencer for term %d", t.term)
+	t.leader = t.n.ID()
+	for _, node := range t.n.NodeIDs() {
+		t.next[node] = len(t.log)
+		t.match[node] = 0
+	}
+	// entries of earlier terms only become visible behind one of our own
+	t.log = append(t.log, orderEntry{Term: t.term, Noop: true})
+	t.match[t.n.ID()] = len(t.log)
+}
+
+// sendAppend replicates the log suffix a follower is missing. Must be called with mu held.
+func (t *totalOrder) sendAppend(node string) {
+	start := t.next[node]
+	if start > len(t.log) {
+		start = len(t.log)
+	}
+	end := len(t.log)
+	if end-start > MAX_APPEND {
+		end = start + MAX_APPEND
+	}
+	prevTerm := 0
+	if start > 0 {
+		prevTerm = t.log[start-1].Term
+	}
+	entries := make([]orderEntry, end-start)
+	copy(entries, t.log[start:end])
+
+	t.n.RPC(node, appendBody{
+		Type:     "to_append",
+		Term:     t.term,
+		Start:    start,
+		PrevTerm: prevTerm,
+		Entries:  entries,
+		Commit:   t.commit,
+	}, t.handleAppendRes)
+}
+
+func (t *totalOrder) handleVoteRes(msg maelstrom.Message) error {
+	var body voteResBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if body.Term > t.term {
+		t.stepDown(body.Term)
+		return nil
+	}
+	if body.Term == t.term && body.Granted {
+		t.votes[msg.Src] = true
+		t.winIfMajority()
+	}
+	return nil
+}
+
+func (t *totalOrder) handleAppendRes(msg maelstrom.Message) error {
+	var body appendResBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if body.Term > t.term {
+		t.stepDown(body.Term)
+		t.resetDeadline()
+		return nil
+	}
+	if t.leader != t.n.ID() || body.Term != t.term {
+		return nil
+	}
+	if body.Ok {
+		if body.Match > t.match[msg.Src] {
+			t.match[msg.Src] = body.Match
+		}
+		t.next[msg.Src] = body.Match
+		t.advance()
+	} else {
+		t.next[msg.Src] = body.Match
+	}
+	return nil
+}
+
+func (t *totalOrder) handleVote(msg maelstrom.Message) error {
+	var body voteBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	if body.Term > t.term {
+		t.stepDown(body.Term)
+	}
+	upToDate := body.LastTerm > t.lastTerm() ||
+		(body.LastTerm == t.lastTerm() && body.LastIndex >= len(t.log))
+	granted := body.Term == t.term && (t.votedFor == "" || t.votedFor == msg.Src) && upToDate
+	if granted {
+		t.votedFor = msg.Src
+		t.resetDeadline()
+	}
+	res := voteResBody{Type: "to_vote_ok", Term: t.term, Granted: granted}
+	t.mu.Unlock()
+
+	return t.n.Reply(msg, res)
+}
+
+func (t *totalOrder) handleAppend(msg maelstrom.Message) error {
+	var body appendBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	res := t.appendEntries(msg.Src, body)
+	t.mu.Unlock()
+
+	return t.n.Reply(msg, res)
+}
+
+// appendEntries merges the sequencer's entries into the local log. Must be
+// called with mu held.
+func (t *totalOrder) appendEntries(src string, body appendBody) appendResBody {
+	if body.Term < t.term {
+		return appendResBody{Type: "to_append_ok", Term: t.term, Ok: false, Match: body.Start}
+	}
+	if body.Term > t.term || t.leader != src {
+		t.stepDown(body.Term)
+		t.leader = src
+	}
+	t.resetDeadline()
+
+	if body.Start > len(t.log) {
+		return appendResBody{Type: "to_append_ok", Term: t.term, Ok: false, Match: len(t.log)}
+	}
+	if body.Start > 0 && t.log[body.Start-1].Term != body.PrevTerm {
+		// back off to the commit point, which is known to match
+		return appendResBody{Type: "to_append_ok", Term: t.term, Ok: false, Match: t.commit}
+	}
+
+	for i, e := range body.Entries {
+		idx := body.Start + i
+		if idx < len(t.log) {
+			if t.log[idx].Term == e.Term {
+				continue
+			}
+			t.truncate(idx)
+		}
+		t.log = append(t.log, e)
+		if !e.Noop {
+			t.logged[e.Value]++
+		}
+	}
+
+	match := body.Start + len(body.Entries)
+	commit := body.Commit
+	if commit > match {
+		commit = match
+	}
+	t.apply(commit)
+
+	return appendResBody{Type: "to_append_ok", Term: t.term, Ok: true, Match: match}
+}
+
+func (t *totalOrder) handleSubmit(msg maelstrom.Message) error {
+	var body submitBody
+	if err := json.Unmarshal(msg.Body, &body); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	if t.leader == t.n.ID() {
+		t.appendLocal(body.Messages)
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *totalOrder) run(tick time.Duration) {
+	for range time.Tick(tick) {
+		t.tick()
+	}
+}
+
+func (t *totalOrder) register(n *maelstrom.Node) {
+	n.Handle("to_submit", t.handleSubmit)
+	n.Handle("to_vote", t.handleVote)
+	n.Handle("to_append", t.handleAppend)
+}
+
+func keys(set map[int]bool) []int {
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+var totalCluster = []string{"n1", "n2", "n3"}
+
+func testTotalOrder(self string, term int, entries []orderEntry, commit int) *totalOrder {
+	t := createTotalOrder(testNode(self, totalCluster), createBroadcastSvc(time.Second), time.Second)
+	t.term = term
+	for _, e := range entries {
+		t.log = append(t.log, e)
+		if !e.Noop {
+			t.logged[e.Value]++
+		}
+	}
+	t.apply(commit)
+	return t
+}
+
+func message(src string, body any) maelstrom.Message {
+	raw, _ := json.Marshal(body)
+	return maelstrom.Message{Src: src, Body: raw}
+}
+
+// lastSent decodes the last message the node wrote into body.
+func lastSent(t *testing.T, out *bytes.Buffer, body any) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var msg maelstrom.Message
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &msg); err != nil {
+		t.Fatalf("decode sent message: %v", err)
+	}
+	if err := json.Unmarshal(msg.Body, body); err != nil {
+		t.Fatalf("decode sent body: %v", err)
+	}
+}
+
+// logged lists how many entries hold each value, without the values that
+// were truncated away.
+func logged(t *totalOrder) map[int]int {
+	counts := make(map[int]int)
+	for v, c := range t.logged {
+		if c != 0 {
+			counts[v] = c
+		}
+	}
+	return counts
+}
+
+func TestTotalOrderAppendEntries(t *testing.T) {
+	type args struct {
+		term    int
+		log     []orderEntry
+		commit  int
+		request appendBody
+	}
+	type want struct {
+		res     appendResBody
+		log     []orderEntry
+		logged  map[int]int
+		ordered []int
+		leader  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "appends to an empty log and applies the commit",
+			args: args{
+				term:    1,
+				log:     []orderEntry{},
+				request: appendBody{Term: 1, Start: 0, Entries: []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}}, Commit: 1},
+			},
+			want: want{
+				res:     appendResBody{Type: "to_append_ok", Term: 1, Ok: true, Match: 2},
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}},
+				logged:  map[int]int{10: 1, 11: 1},
+				ordered: []int{10},
+				leader:  "n1",
+			},
+		},
+		{
+			name: "conflicting suffix of an older term is replaced",
+			args: args{
+				term:    1,
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}, {Term: 1, Value: 12}},
+				commit:  1,
+				request: appendBody{Term: 2, Start: 1, PrevTerm: 1, Entries: []orderEntry{{Term: 2, Noop: true}, {Term: 2, Value: 20}}},
+			},
+			want: want{
+				res:     appendResBody{Type: "to_append_ok", Term: 2, Ok: true, Match: 3},
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 2, Noop: true}, {Term: 2, Value: 20}},
+				logged:  map[int]int{10: 1, 20: 1},
+				ordered: []int{10},
+				leader:  "n1",
+			},
+		},
+		{
+			name: "entries already stored are kept",
+			args: args{
+				term:    1,
+				log:     []orderEntry{{Term: 1, Value: 10}},
+				request: appendBody{Term: 1, Start: 0, Entries: []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}}, Commit: 2},
+			},
+			want: want{
+				res:     appendResBody{Type: "to_append_ok", Term: 1, Ok: true, Match: 2},
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}},
+				logged:  map[int]int{10: 1, 11: 1},
+				ordered: []int{10, 11},
+				leader:  "n1",
+			},
+		},
+		{
+			name: "commit stops at the match and skips no-ops",
+			args: args{
+				term:    1,
+				log:     []orderEntry{},
+				request: appendBody{Term: 1, Start: 0, Entries: []orderEntry{{Term: 1, Noop: true}, {Term: 1, Value: 10}}, Commit: 5},
+			},
+			want: want{
+				res:     appendResBody{Type: "to_append_ok", Term: 1, Ok: true, Match: 2},
+				log:     []orderEntry{{Term: 1, Noop: true}, {Term: 1, Value: 10}},
+				logged:  map[int]int{10: 1},
+				ordered: []int{10},
+				leader:  "n1",
+			},
+		},
+		{
+			name: "mismatched previous term backs off to the commit",
+			args: args{
+				term:    2,
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}},
+				commit:  1,
+				request: appendBody{Term: 2, Start: 2, PrevTerm: 2, Entries: []orderEntry{{Term: 2, Value: 20}}},
+			},
+			want: want{
+				res:     appendResBody{Type: "to_append_ok", Term: 2, Ok: false, Match: 1},
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}},
+				logged:  map[int]int{10: 1, 11: 1},
+				ordered: []int{10},
+				leader:  "n1",
+			},
+		},
+		{
+			name: "gap reports the end of the log",
+			args: args{
+				term:    1,
+				log:     []orderEntry{{Term: 1, Value: 10}},
+				request: appendBody{Term: 1, Start: 3, PrevTerm: 1, Entries: []orderEntry{{Term: 1, Value: 13}}},
+			},
+			want: want{
+				res:     appendResBody{Type: "to_append_ok", Term: 1, Ok: false, Match: 1},
+				log:     []orderEntry{{Term: 1, Value: 10}},
+				logged:  map[int]int{10: 1},
+				ordered: []int{},
+				leader:  "n1",
+			},
+		},
+		{
+			name: "stale sequencer is rejected",
+			args: args{
+				term:    3,
+				log:     []orderEntry{},
+				request: appendBody{Term: 2, Start: 0, Entries: []orderEntry{{Term: 2, Value: 20}}, Commit: 1},
+			},
+			want: want{
+				res:     appendResBody{Type: "to_append_ok", Term: 3, Ok: false, Match: 0},
+				log:     []orderEntry{},
+				logged:  map[int]int{},
+				ordered: []int{},
+				leader:  "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := testTotalOrder("n2", tt.args.term, tt.args.log, tt.args.commit)
+
+			res := to.appendEntries("n1", tt.args.request)
+			got := want{res: res, log: to.log, logged: logged(to), ordered: to.read(), leader: to.leader}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("appendEntries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTotalOrderHandleVote(t *testing.T) {
+	type args struct {
+		term     int
+		votedFor string
+		log      []orderEntry
+		request  voteBody
+	}
+	type want struct {
+		res      voteResBody
+		votedFor string
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "newer term with an up-to-date log",
+			args: args{
+				term:    1,
+				log:     []orderEntry{{Term: 1, Value: 10}},
+				request: voteBody{Type: "to_vote", Term: 2, LastIndex: 1, LastTerm: 1},
+			},
+			want: want{res: voteResBody{Type: "to_vote_ok", Term: 2, Granted: true}, votedFor: "n1"},
+		},
+		{
+			name: "shorter log of the same last term",
+			args: args{
+				term:    1,
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}},
+				request: voteBody{Type: "to_vote", Term: 2, LastIndex: 1, LastTerm: 1},
+			},
+			want: want{res: voteResBody{Type: "to_vote_ok", Term: 2, Granted: false}, votedFor: ""},
+		},
+		{
+			name: "newer last term beats a longer log",
+			args: args{
+				term:    2,
+				log:     []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}},
+				request: voteBody{Type: "to_vote", Term: 3, LastIndex: 1, LastTerm: 2},
+			},
+			want: want{res: voteResBody{Type: "to_vote_ok", Term: 3, Granted: true}, votedFor: "n1"},
+		},
+		{
+			name: "one vote per term",
+			args: args{
+				term:     2,
+				votedFor: "n3",
+				log:      []orderEntry{},
+				request:  voteBody{Type: "to_vote", Term: 2, LastIndex: 5, LastTerm: 2},
+			},
+			want: want{res: voteResBody{Type: "to_vote_ok", Term: 2, Granted: false}, votedFor: "n3"},
+		},
+		{
+			name: "stale term",
+			args: args{
+				term:    3,
+				log:     []orderEntry{},
+				request: voteBody{Type: "to_vote", Term: 2, LastIndex: 5, LastTerm: 2},
+			},
+			want: want{res: voteResBody{Type: "to_vote_ok", Term: 3, Granted: false}, votedFor: ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := testTotalOrder("n2", tt.args.term, tt.args.log, 0)
+			to.votedFor = tt.args.votedFor
+			var out bytes.Buffer
+			to.n.Stdout = &out
+
+			if err := to.handleVote(message("n1", tt.args.request)); err != nil {
+				t.Fatalf("handleVote() error = %v", err)
+			}
+			var res voteResBody
+			lastSent(t, &out, &res)
+			got := want{res: res, votedFor: to.votedFor}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleVote() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTotalOrderAdvance(t *testing.T) {
+	type args struct {
+		log   []orderEntry
+		match map[string]int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []int
+	}{
+		{
+			name: "commits what a majority stored",
+			args: args{
+				log:   []orderEntry{{Term: 2, Value: 10}, {Term: 2, Value: 11}, {Term: 2, Value: 12}},
+				match: map[string]int{"n1": 3, "n2": 2, "n3": 0},
+			},
+			want: []int{10, 11},
+		},
+		{
+			name: "no majority commits nothing",
+			args: args{
+				log:   []orderEntry{{Term: 2, Value: 10}},
+				match: map[string]int{"n1": 1, "n2": 0, "n3": 0},
+			},
+			want: []int{},
+		},
+		{
+			name: "entries of earlier terms wait for one of the current term",
+			args: args{
+				log:   []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}, {Term: 2, Noop: true}},
+				match: map[string]int{"n1": 3, "n2": 2, "n3": 2},
+			},
+			want: []int{},
+		},
+		{
+			name: "entries of earlier terms commit behind the no-op",
+			args: args{
+				log:   []orderEntry{{Term: 1, Value: 10}, {Term: 1, Value: 11}, {Term: 2, Noop: true}},
+				match: map[string]int{"n1": 3, "n2": 3, "n3": 0},
+			},
+			want: []int{10, 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := testTotalOrder("n1", 2, tt.args.log, 0)
+			to.leader = "n1"
+			to.match = tt.args.match
+
+			to.advance()
+			if got := to.read(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("advance() delivered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTotalOrderElection(t *testing.T) {
+	to := testTotalOrder("n1", 1, []orderEntry{{Term: 1, Value: 10}}, 0)
+	to.deadline = time.Now().Add(-time.Second)
+
+	to.tick()
+	if to.term != 2 || to.votedFor != "n1" || to.leader != "" {
+		t.Fatalf("after timeout term = %d, votedFor = %q, leader = %q, want 2, n1, none", to.term, to.votedFor, to.leader)
+	}
+
+	if err := to.handleVoteRes(message("n2", voteResBody{Type: "to_vote_ok", Term: 2, Granted: true})); err != nil {
+		t.Fatalf("handleVoteRes() error = %v", err)
+	}
+	if to.leader != "n1" {
+		t.Fatalf("leader = %q after a majority of votes, want n1", to.leader)
+	}
+	if want := []orderEntry{{Term: 1, Value: 10}, {Term: 2, Noop: true}}; !reflect.DeepEqual(to.log, want) {
+		t.Fatalf("log = %v, want %v with the no-op of the new term", to.log, want)
+	}
+
+	// the inherited entry only becomes visible once the no-op is stored by a majority
+	ok := func(match int) error {
+		return to.handleAppendRes(message("n2", appendResBody{Type: "to_append_ok", Term: 2, Ok: true, Match: match}))
+	}
+	if err := ok(1); err != nil {
+		t.Fatalf("handleAppendRes() error = %v", err)
+	}
+	if got := to.read(); len(got) != 0 {
+		t.Fatalf("read() = %v before the no-op is stored, want nothing", got)
+	}
+	if err := ok(2); err != nil {
+		t.Fatalf("handleAppendRes() error = %v", err)
+	}
+	if got, want := to.read(), []int{10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("read() = %v, want %v", got, want)
+	}
+
+	// a newer term from a follower ends the reign
+	if err := to.handleAppendRes(message("n3", appendResBody{Type: "to_append_ok", Term: 3, Ok: false})); err != nil {
+		t.Fatalf("handleAppendRes() error = %v", err)
+	}
+	if to.leader != "" || to.term != 3 {
+		t.Errorf("leader = %q, term = %d after a newer term, want none, 3", to.leader, to.term)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/topology"
+)
+
+// topoviz prints the topology a strategy builds for nodes n0..n<count-1>,
+// e.g. `topoviz -nodes 25 -topology tree:5 | dot -Tpng -o tree.png`.
+// With -latency, edges are labeled with the round trips read from a JSON
+// file like {"n0": {"n1": 12.5}}, in milliseconds, and strategies such as
+// weighted build from them.
+func main() {
+	count := flag.Int("nodes", 25, "number of nodes")
+	spec := flag.String("topology", "tree:5", "topology strategy, as in BROADCAST_TOPOLOGY")
+	format := flag.String("format", "dot", "output format, dot or json")
+	metrics := flag.Bool("metrics", false, "print topology metrics to stderr")
+	latencyFile := flag.String("latency", "", "JSON file of round trips between nodes in milliseconds")
+	flag.Parse()
+
+	if *count < 1 {
+		log.Fatalf("-nodes must be at least 1, got %d", *count)
+	}
+
+	strategy, err := topology.Parse(*spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if strategy.NeedsGiven {
+		log.Fatalf("topology %q passes through Maelstrom's topology message, which topoviz does not have", *spec)
+	}
+
+	nodes := make([]string, *count)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("n%d", i)
+	}
+	var in topology.Input
+	var labels map[topology.Edge]string
+	if *latencyFile != "" {
+		in.Latency, labels, err = readLatency(*latencyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// the graph is printed as built, so a broken strategy can be inspected
+	graph := strategy.Generate(nodes, in)
+
+	if *metrics {
+		fmt.Fprintf(os.Stderr, "%+v\n", topology.Analyze(graph))
+		if err := strategy.Validate(graph, nodes); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid: %v\n", err)
+		}
+	}
+
+	switch *format {
+	case "dot":
+		fmt.Print(topology.DOT(graph, labels))
+	case "json":
+		buf, err := topology.JSON(graph)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(buf))
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+}
+
+// readLatency reads round trips in milliseconds per pair of nodes and
+// returns them along with an edge label for each.
+func readLatency(path string) (map[string]map[string]time.Duration, map[topology.Edge]string, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var millis map[string]map[string]float64
+	if err := json.Unmarshal(buf, &millis); err != nil {
+		return nil, nil, fmt.Errorf("latency file %s: %w", path, err)
+	}
+
+	latency := make(map[string]map[string]time.Duration)
+	labels := make(map[topology.Edge]string)
+	for from, row := range millis {
+		latency[from] = make(map[string]time.Duration)
+		for to, ms := range row {
+			if ms < 0 {
+				return nil, nil, fmt.Errorf("latency file %s: negative round trip %s -> %s", path, from, to)
+			}
+			latency[from][to] = time.Duration(ms * float64(time.Millisecond))
+			labels[topology.Edge{from, to}] = fmt.Sprintf("%gms", ms)
+		}
+	}
+	return latency, labels, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/env"
+	"github.com/AxelUser/dist-sys-challenge/internal/idgen"
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// The epoch is settled while init waits, so seq-kv gets at most three
+// seconds before the node gives up.
+const EPOCH_ATTEMPTS = 6
+const EPOCH_TIMEOUT_MILL = 500
+
+// epochStore keeps epochs in a file per node under UNIQUE_ID_EPOCH_DIR when
+// it is set and in seq-kv under epoch-<node> otherwise.
+func epochStore(n *maelstrom.Node) idgen.EpochStore {
+	if dir := env.String("UNIQUE_ID_EPOCH_DIR", ""); dir != "" {
+		return idgen.FileEpochs{Path: filepath.Join(dir, n.ID()+".epoch")}
+	}
+	return idgen.KVEpochs{
+		KV:       maelstrom.NewSeqKV(n),
+		Key:      fmt.Sprintf("epoch-%s", n.ID()),
+		Attempts: EPOCH_ATTEMPTS,
+		Timeout:  EPOCH_TIMEOUT_MILL * time.Millisecond,
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/AxelUser/dist-sys-challenge/internal/env"
+	"github.com/AxelUser/dist-sys-challenge/internal/idgen"
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// Snowflake IDs stay below a horizon saved HORIZON_WINDOW_MILL ahead of the
+// clock. It is saved again every HORIZON_RENEW_MILL, so a node keeps issuing
+// IDs while a save or two is lost. Like the epoch, the first horizon is saved
+// while init waits.
+const HORIZON_WINDOW_MILL = 1000
+const HORIZON_RENEW_MILL = 250
+const HORIZON_ATTEMPTS = 6
+const HORIZON_TIMEOUT_MILL = 500
+
+// horizonStore keeps the Snowflake horizon next to the epoch: in a file per
+// node under UNIQUE_ID_EPOCH_DIR when it is set and in seq-kv under
+// snowflake-horizon-<node> otherwise.
+func horizonStore(n *maelstrom.Node) idgen.HorizonStore {
+	if dir := env.String("UNIQUE_ID_EPOCH_DIR", ""); dir != "" {
+		return idgen.FileHorizon{Path: filepath.Join(dir, n.ID()+".horizon")}
+	}
+	return idgen.KVHorizon{
+		KV:      maelstrom.NewSeqKV(n),
+		Key:     fmt.Sprintf("snowflake-horizon-%s", n.ID()),
+		Timeout: HORIZON_TIMEOUT_MILL * time.Millisecond,
+	}
+}
+
+// resumeSnowflake continues sf after the horizon of the previous run, saves
+// the first horizon of this run and keeps moving it forward.
+func resumeSnowflake(sf *idgen.Snowflake, store idgen.HorizonStore) error {
+	var saved int64
+	var err error
+	for attempt := 0; attempt < HORIZON_ATTEMPTS; attempt++ {
+		var prev int64
+		if prev, err = store.Load(); err != nil {
+			continue
+		}
+		sf.Resume(prev)
+		if saved, err = extendHorizon(sf, store, prev+HORIZON_WINDOW_MILL); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("snowflake horizon after %d attempts: %w", HORIZON_ATTEMPTS, err)
+	}
+
+	go func() {
+		for range time.Tick(HORIZON_RENEW_MILL * time.Millisecond) {
+			horizon, err := extendHorizon(sf, store, saved)
+			if err != nil {
+				log.Printf("Failed to extend snowflake horizon: %v", err)
+				continue
+			}
+			saved = horizon
+		}
+	}()
+	return nil
+}
+
+// extendHorizon saves a horizon HORIZON_WINDOW_MILL ahead of the clock, but
+// not before floor, and lets sf issue IDs up to it once it is saved. The
+// saved horizon never moves back, even when the clock does.
+func extendHorizon(sf *idgen.Snowflake, store idgen.HorizonStore, floor int64) (int64, error) {
+	horizon := time.Since(idgen.Epoch).Milliseconds() + HORIZON_WINDOW_MILL
+	if horizon < floor {
+		horizon = floor
+	}
+	if err := store.Save(horizon); err != nil {
+		return 0, err
+	}
+	sf.Extend(horizon)
+	return horizon, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+const LEASE_BLOCK = 1000
+const LEASE_KEY = "ids"
+const LEASE_TIMEOUT_MILL = 500 // per lin-kv request
+const LEASE_RETRY_MILL = 100   // pause after a failed lease
+const LEASE_WAIT_MILL = 1000   // how long generate waits for a block
+
+// leaseBlocks reserves blocks by moving the counter under LEASE_KEY forward
+// with compare-and-swap, so every block goes to exactly one node.
+func leaseBlocks(kv *maelstrom.KV) func(size uint64) (uint64, error) {
+	return func(size uint64) (uint64, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), LEASE_TIMEOUT_MILL*time.Millisecond)
+		defer cancel()
+
+		cur, err := kv.ReadInt(ctx, LEASE_KEY)
+		if err != nil && maelstrom.ErrorCode(err) != maelstrom.KeyDoesNotExist {
+			log.Printf("Failed to read the ID counter: %v", err)
+			return 0, err
+		}
+		if err := kv.CompareAndSwap(ctx, LEASE_KEY, cur, cur+int(size), true); err != nil {
+			log.Printf("Failed to lease IDs from %d: %v", cur, err)
+			return 0, err
+		}
+
+		log.Printf("Leased IDs %d..%d", cur, cur+int(size)-1)
+		return uint64(cur), nil
+	}
+}
+
+// leaseUnavailable turns a missing block into an error Maelstrom may retry.
+func leaseUnavailable(err error) error {
+	return maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, fmt.Sprintf("no IDs to hand out: %v", err))
+}
@@ -1,42 +1,207 @@
 package main
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"log"
-	"sync/atomic"
+	"sync"
+	"time"
 
+	"github.com/AxelUser/dist-sys-challenge/internal/env"
+	"github.com/AxelUser/dist-sys-challenge/internal/idgen"
 	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
 )
 
+const DEFAULT_FORMAT = "node-counter"
+const MAX_BATCH = 10000
+
+type generateBatchBody struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// idGenerator issues count IDs at once.
+type idGenerator func(count int) ([]any, error)
+
 type uniqueIdSvc struct {
-	count uint64
+	format string
+	mu     sync.RWMutex
+	next   idGenerator // set on init, formats need the cluster
 }
 
-func createUniqueIdSvc() *uniqueIdSvc {
+func createUniqueIdSvc(format string) *uniqueIdSvc {
 	return &uniqueIdSvc{
-		count: 0,
+		format: format,
+	}
+}
+
+// formats build the generator of each ID format once the node knows the
+// cluster.
+var formats = map[string]func(n *maelstrom.Node) (idGenerator, error){
+	"node-counter": func(n *maelstrom.Node) (idGenerator, error) {
+		epoch, err := epochStore(n).Next()
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Starting boot epoch %d", epoch)
+
+		c := idgen.NewCounter(n.ID(), epoch)
+		return func(count int) ([]any, error) {
+			return values(c.NextN(count)), nil
+		}, nil
+	},
+	"snowflake": func(n *maelstrom.Node) (idGenerator, error) {
+		sf, err := idgen.NewSnowflake(nodeIndex(n), time.Now)
+		if err != nil {
+			return nil, fmt.Errorf("snowflake IDs for %s: %w", n.ID(), err)
+		}
+		if err := resumeSnowflake(sf, horizonStore(n)); err != nil {
+			return nil, err
+		}
+		return func(count int) ([]any, error) {
+			ids, err := sf.NextN(count)
+			if err != nil {
+				return nil, maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, err.Error())
+			}
+			return values(ids), nil
+		}, nil
+	},
+	"ulid": func(n *maelstrom.Node) (idGenerator, error) {
+		u := idgen.NewULID(time.Now, rand.Reader)
+		return func(count int) ([]any, error) {
+			ids, err := u.NextN(count)
+			return values(ids), err
+		}, nil
+	},
+	"ksuid": func(n *maelstrom.Node) (idGenerator, error) {
+		k := idgen.NewKSUID(time.Now, rand.Reader)
+		return func(count int) ([]any, error) {
+			ids, err := k.NextN(count)
+			return values(ids), err
+		}, nil
+	},
+	"lease": func(n *maelstrom.Node) (idGenerator, error) {
+		size := uint64(env.Int("UNIQUE_ID_LEASE_BLOCK", LEASE_BLOCK))
+		l, err := idgen.NewLease(leaseBlocks(maelstrom.NewLinKV(n)), size, LEASE_RETRY_MILL*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+		l.Prefetch()
+		return func(count int) ([]any, error) {
+			ids, err := l.Take(count, LEASE_WAIT_MILL*time.Millisecond)
+			if err != nil {
+				return nil, leaseUnavailable(err)
+			}
+			return values(ids), nil
+		}, nil
+	},
+}
+
+// nodeIndex is the position of the node in the cluster, the same on every
+// node.
+func nodeIndex(n *maelstrom.Node) int {
+	for i, id := range n.NodeIDs() {
+		if id == n.ID() {
+			return i
+		}
+	}
+	return -1
+}
+
+func (svc *uniqueIdSvc) start(n *maelstrom.Node) error {
+	next, err := formats[svc.format](n)
+	if err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	svc.next = next
+	svc.mu.Unlock()
+	return nil
+}
+
+func (svc *uniqueIdSvc) ids(count int) ([]any, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	if svc.next == nil {
+		return nil, maelstrom.NewRPCError(maelstrom.TemporarilyUnavailable, "node is not initialized")
 	}
+	return svc.next(count)
 }
 
-func (svc *uniqueIdSvc) generate(node string) string {
-	return fmt.Sprintf("%s-%d", node, atomic.AddUint64(&svc.count, 1))
+func values[T any](ids []T) []any {
+	vs := make([]any, len(ids))
+	for i, id := range ids {
+		vs[i] = id
+	}
+	return vs
+}
+
+// reply works like n.Reply, which decodes the body into float64 numbers
+// to add in_reply_to and so rounds 64-bit IDs.
+func reply(n *maelstrom.Node, msg maelstrom.Message, body map[string]any) error {
+	var req maelstrom.MessageBody
+	if err := json.Unmarshal(msg.Body, &req); err != nil {
+		return err
+	}
+	body["in_reply_to"] = req.MsgID
+	return n.Send(msg.Src, body)
 }
 
 func main() {
-	svc := createUniqueIdSvc()
+	format := env.String("UNIQUE_ID_FORMAT", DEFAULT_FORMAT)
+	if _, ok := formats[format]; !ok {
+		log.Fatalf("unknown ID format %q", format)
+	}
+	log.Printf("Using ID format %s", format)
+
+	svc := createUniqueIdSvc(format)
 	n := maelstrom.NewNode()
 
+	// a node that cannot issue IDs must not look healthy to Maelstrom
+	n.Handle("init", func(msg maelstrom.Message) error {
+		if err := svc.start(n); err != nil {
+			log.Fatalf("Failed to start %s IDs: %v", format, err)
+		}
+		return nil
+	})
+
 	n.Handle("generate", func(msg maelstrom.Message) error {
 		var body map[string]any
 		if err := json.Unmarshal(msg.Body, &body); err != nil {
 			return err
 		}
 
+		ids, err := svc.ids(1)
+		if err != nil {
+			return err
+		}
+
 		body["type"] = "generate_ok"
-		body["id"] = svc.generate(n.ID())
+		body["id"] = ids[0]
+
+		return reply(n, msg, body)
+	})
+
+	n.Handle("generate_batch", func(msg maelstrom.Message) error {
+		var body generateBatchBody
+		if err := json.Unmarshal(msg.Body, &body); err != nil {
+			return err
+		}
+		if body.Count < 1 || body.Count > MAX_BATCH {
+			return maelstrom.NewRPCError(maelstrom.MalformedRequest, fmt.Sprintf("count must be between 1 and %d, got %d", MAX_BATCH, body.Count))
+		}
+
+		ids, err := svc.ids(body.Count)
+		if err != nil {
+			return err
+		}
 
-		return n.Reply(msg, body)
+		return reply(n, msg, map[string]any{
+			"type": "generate_batch_ok",
+			"ids":  ids,
+		})
 	})
 
 	if err := n.Run(); err != nil {
@@ -0,0 +1,36 @@
+// Package env reads node options from the environment. Invalid values stop
+// the node right away, before it takes part in any run.
+package env
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// String reads a string from the environment.
+func String(name string, def string) string {
+	if raw, ok := os.LookupEnv(name); ok {
+		return raw
+	}
+	return def
+}
+
+// Int reads a positive integer from the environment.
+func Int(name string, def int) int {
+	raw, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		log.Fatalf("invalid %s=%q: expected positive integer", name, raw)
+	}
+	return v
+}
+
+// Millis reads a duration in milliseconds from the environment.
+func Millis(name string, def int) time.Duration {
+	return time.Duration(Int(name, def)) * time.Millisecond
+}
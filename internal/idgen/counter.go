@@ -0,0 +1,38 @@
+package idgen
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Counter issues IDs of the form node-epoch-count. The count starts over
+// with every process, so the boot epoch, which must grow with every start
+// of the node, keeps a restarted node from repeating the IDs of an earlier
+// run.
+type Counter struct {
+	node  string
+	epoch uint64
+	count uint64
+}
+
+func NewCounter(node string, epoch uint64) *Counter {
+	return &Counter{node: node, epoch: epoch}
+}
+
+func (c *Counter) Next() string {
+	return c.format(atomic.AddUint64(&c.count, 1))
+}
+
+// NextN reserves count consecutive IDs with a single add.
+func (c *Counter) NextN(count int) []string {
+	first := atomic.AddUint64(&c.count, uint64(count)) - uint64(count) + 1
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = c.format(first + uint64(i))
+	}
+	return ids
+}
+
+func (c *Counter) format(n uint64) string {
+	return fmt.Sprintf("%s-%d-%d", c.node, c.epoch, n)
+}
@@ -0,0 +1,65 @@
+package idgen
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCounterSurvivesRestart(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) EpochStore
+	}{
+		{
+			name: "file",
+			store: func(t *testing.T) EpochStore {
+				return FileEpochs{Path: filepath.Join(t.TempDir(), "n1.epoch")}
+			},
+		},
+		{
+			name: "seq-kv",
+			store: func(t *testing.T) EpochStore {
+				return KVEpochs{KV: newFakeKV(), Key: "epoch-n1", Attempts: 3, Timeout: time.Second}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store(t)
+			seen := make(map[string]bool)
+
+			// every run starts counting from zero, like a restarted process
+			var prev uint64
+			for run := 0; run < 3; run++ {
+				epoch, err := store.Next()
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				if epoch <= prev {
+					t.Fatalf("run %d got epoch %d after %d, want growing epochs", run, epoch, prev)
+				}
+				prev = epoch
+				c := NewCounter("n1", epoch)
+
+				ids := append(c.NextN(5), c.Next(), c.Next())
+				for _, id := range ids {
+					if seen[id] {
+						t.Fatalf("run %d issued %s again", run, id)
+					}
+					seen[id] = true
+				}
+			}
+		})
+	}
+}
+
+func TestCounter(t *testing.T) {
+	c := NewCounter("n2", 4)
+
+	got := append([]string{c.Next()}, c.NextN(2)...)
+	if want := []string{"n2-4-1", "n2-4-2", "n2-4-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Next(), NextN() = %v, want %v", got, want)
+	}
+}
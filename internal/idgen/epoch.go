@@ -0,0 +1,116 @@
+package idgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// EpochStore persists the boot epoch of a node. Next is called once per
+// start and returns an epoch greater than any it returned before.
+type EpochStore interface {
+	Next() (uint64, error)
+}
+
+// FileEpochs keeps the epoch in a local file. The file is replaced
+// atomically, so a crash while starting never loses an epoch.
+type FileEpochs struct {
+	Path string
+}
+
+func (f FileEpochs) Next() (uint64, error) {
+	epoch, err := readNumber(f.Path)
+	if err != nil {
+		return 0, err
+	}
+	epoch++
+	if err := writeNumber(f.Path, epoch); err != nil {
+		return 0, err
+	}
+	return epoch, nil
+}
+
+// readNumber reads the number stored at path, 0 when there is no file.
+func readNumber(path string) (uint64, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("file %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// writeNumber replaces the file at path through a synced temporary file,
+// so readers see either the old or the new number.
+func writeNumber(path string, v uint64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := fmt.Fprintln(tmp, v); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// EpochKV is the part of a Maelstrom key-value store KVEpochs needs.
+type EpochKV interface {
+	ReadInt(ctx context.Context, key string) (int, error)
+	CompareAndSwap(ctx context.Context, key string, from, to any, createIfNotExists bool) error
+}
+
+// KVEpochs keeps the epoch under Key in a key-value store. Epochs only
+// grow, so a compare-and-swap from a stale read fails instead of handing
+// out an epoch twice. Next gives up after Attempts tries of at most Timeout
+// each, so a start never waits longer than their product.
+type KVEpochs struct {
+	KV       EpochKV
+	Key      string
+	Attempts int
+	Timeout  time.Duration
+}
+
+func (e KVEpochs) Next() (uint64, error) {
+	err := errors.New("no attempts")
+	for i := 0; i < e.Attempts; i++ {
+		var cur int
+		if cur, err = e.increment(); err == nil {
+			return uint64(cur + 1), nil
+		}
+	}
+	return 0, fmt.Errorf("boot epoch %s after %d attempts: %w", e.Key, e.Attempts, err)
+}
+
+// increment returns the epoch it moved forward.
+func (e KVEpochs) increment() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+
+	cur, err := e.KV.ReadInt(ctx, e.Key)
+	if err != nil && maelstrom.ErrorCode(err) != maelstrom.KeyDoesNotExist {
+		return 0, err
+	}
+	return cur, e.KV.CompareAndSwap(ctx, e.Key, cur, cur+1, true)
+}
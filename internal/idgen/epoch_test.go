@@ -0,0 +1,189 @@
+package idgen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+func TestFileEpochs(t *testing.T) {
+	type args struct {
+		content *string // nil for no file
+		starts  int
+	}
+	seven := "7\n"
+	tests := []struct {
+		name string
+		args args
+		want []uint64
+	}{
+		{
+			name: "first start",
+			args: args{starts: 3},
+			want: []uint64{1, 2, 3},
+		},
+		{
+			name: "existing file",
+			args: args{content: &seven, starts: 2},
+			want: []uint64{8, 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "n1.epoch")
+			if tt.args.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.args.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := make([]uint64, 0)
+			for i := 0; i < tt.args.starts; i++ {
+				epoch, err := FileEpochs{Path: path}.Next()
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				got = append(got, epoch)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileEpochsRejectsGarbage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "n1.epoch")
+	if err := os.WriteFile(path, []byte("seven"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (FileEpochs{Path: path}).Next(); err == nil {
+		t.Errorf("Next() error = nil, want unparsable epoch")
+	}
+}
+
+// fakeKV behaves like seq-kv for a single key. It can serve stale reads
+// and be cut off.
+type fakeKV struct {
+	mu     sync.Mutex
+	values map[string]int
+	stale  int // reads left that return the value before the last write
+	prev   map[string]int
+	down   bool
+	calls  int
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{values: make(map[string]int), prev: make(map[string]int)}
+}
+
+func (kv *fakeKV) ReadInt(_ context.Context, key string) (int, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.calls++
+	if kv.down {
+		return 0, context.DeadlineExceeded
+	}
+	if kv.stale > 0 {
+		kv.stale--
+		return kv.prev[key], nil
+	}
+	v, ok := kv.values[key]
+	if !ok {
+		return 0, maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+	}
+	return v, nil
+}
+
+func (kv *fakeKV) CompareAndSwap(_ context.Context, key string, from, to any, createIfNotExists bool) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.down {
+		return context.DeadlineExceeded
+	}
+	v, ok := kv.values[key]
+	switch {
+	case !ok && !createIfNotExists:
+		return maelstrom.NewRPCError(maelstrom.KeyDoesNotExist, "key does not exist")
+	case ok && v != from.(int):
+		return maelstrom.NewRPCError(maelstrom.PreconditionFailed, "current value differs")
+	}
+	kv.prev[key] = v
+	kv.values[key] = to.(int)
+	return nil
+}
+
+func (kv *fakeKV) Write(_ context.Context, key string, value any) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.down {
+		return context.DeadlineExceeded
+	}
+	kv.prev[key] = kv.values[key]
+	kv.values[key] = int(value.(int64))
+	return nil
+}
+
+func TestKVEpochs(t *testing.T) {
+	type args struct {
+		stale  int // stale reads before the last start
+		starts int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []uint64
+	}{
+		{
+			name: "every start moves the epoch forward",
+			args: args{starts: 3},
+			want: []uint64{1, 2, 3},
+		},
+		{
+			name: "stale reads are retried",
+			args: args{stale: 2, starts: 3},
+			want: []uint64{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := newFakeKV()
+
+			got := make([]uint64, 0)
+			for i := 0; i < tt.args.starts; i++ {
+				if i == tt.args.starts-1 {
+					kv.stale = tt.args.stale
+				}
+				epoch, err := KVEpochs{KV: kv, Key: "epoch-n1", Attempts: 3, Timeout: time.Second}.Next()
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				got = append(got, epoch)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKVEpochsGivesUp(t *testing.T) {
+	kv := newFakeKV()
+	kv.down = true
+
+	_, err := KVEpochs{KV: kv, Key: "epoch-n1", Attempts: 4, Timeout: time.Second}.Next()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next() error = %v, want the last failure", err)
+	}
+	if kv.calls != 4 {
+		t.Errorf("store read %d times, want one read per attempt", kv.calls)
+	}
+}
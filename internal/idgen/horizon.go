@@ -0,0 +1,70 @@
+package idgen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	maelstrom "github.com/jepsen-io/maelstrom/demo/go"
+)
+
+// HorizonStore persists the Snowflake horizon of a node: the first
+// millisecond since Epoch it may not issue IDs in yet. A node only issues
+// IDs below a horizon that was saved, so after a restart it continues at
+// the saved horizon even if its clock went backwards in between.
+type HorizonStore interface {
+	Load() (int64, error) // 0 when nothing was saved
+	Save(ms int64) error
+}
+
+// FileHorizon keeps the horizon in a local file.
+type FileHorizon struct {
+	Path string
+}
+
+func (f FileHorizon) Load() (int64, error) {
+	v, err := readNumber(f.Path)
+	return int64(v), err
+}
+
+func (f FileHorizon) Save(ms int64) error {
+	return writeNumber(f.Path, uint64(ms))
+}
+
+// HorizonKV is the part of a Maelstrom key-value store KVHorizon needs.
+type HorizonKV interface {
+	ReadInt(ctx context.Context, key string) (int, error)
+	Write(ctx context.Context, key string, value any) error
+}
+
+// KVHorizon keeps the horizon under Key in a key-value store. Only the
+// node itself writes the key, so a plain write is enough.
+type KVHorizon struct {
+	KV      HorizonKV
+	Key     string
+	Timeout time.Duration
+}
+
+func (h KVHorizon) Load() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	ms, err := h.KV.ReadInt(ctx, h.Key)
+	if maelstrom.ErrorCode(err) == maelstrom.KeyDoesNotExist {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("horizon %s: %w", h.Key, err)
+	}
+	return int64(ms), nil
+}
+
+func (h KVHorizon) Save(ms int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	if err := h.KV.Write(ctx, h.Key, ms); err != nil {
+		return fmt.Errorf("horizon %s: %w", h.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package idgen
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHorizonStores(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) HorizonStore
+	}{
+		{
+			name: "file",
+			store: func(t *testing.T) HorizonStore {
+				return FileHorizon{Path: filepath.Join(t.TempDir(), "n1.horizon")}
+			},
+		},
+		{
+			name: "seq-kv",
+			store: func(t *testing.T) HorizonStore {
+				return KVHorizon{KV: newFakeKV(), Key: "snowflake-horizon-n1", Timeout: time.Second}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.store(t)
+
+			got := make([]int64, 0)
+			for _, ms := range []int64{0, 1500, 2500} {
+				if ms > 0 {
+					if err := h.Save(ms); err != nil {
+						t.Fatalf("Save(%d) error = %v", ms, err)
+					}
+				}
+				loaded, err := h.Load()
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				got = append(got, loaded)
+			}
+			if want := []int64{0, 1500, 2500}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Load() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestKVHorizonUnavailable(t *testing.T) {
+	kv := newFakeKV()
+	kv.down = true
+	h := KVHorizon{KV: kv, Key: "snowflake-horizon-n1", Timeout: time.Second}
+
+	if _, err := h.Load(); err == nil {
+		t.Errorf("Load() error = nil, want the store to be unreachable")
+	}
+	if err := h.Save(1000); err == nil {
+		t.Errorf("Save() error = nil, want the store to be unreachable")
+	}
+}
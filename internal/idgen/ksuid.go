@@ -0,0 +1,56 @@
+package idgen
+
+import (
+	"encoding/binary"
+	"io"
+	"math/big"
+	"time"
+)
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// KSUIDEpoch is the zero of KSUID timestamps.
+var KSUIDEpoch = time.Unix(1400000000, 0)
+
+// KSUID issues 27 character base62 IDs in the layout of Segment's KSUIDs:
+// 32 bits of seconds since KSUIDEpoch followed by a 128-bit random payload.
+// Within a second the payload is incremented, so IDs of one node also sort
+// by issue order.
+type KSUID struct {
+	m *monotonic
+}
+
+func NewKSUID(now func() time.Time, random io.Reader) *KSUID {
+	return &KSUID{m: newMonotonic(now, random, KSUIDEpoch, time.Second, 16)}
+}
+
+func (k *KSUID) Next() (string, error) {
+	ids, err := k.NextN(1)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+func (k *KSUID) NextN(count int) ([]string, error) {
+	return k.m.nextN(count, encodeKSUID)
+}
+
+// encodeKSUID writes the 160 bits of timestamp and payload in base62,
+// left-padded with zeros so every ID has the same length.
+func encodeKSUID(s int64, payload []byte) string {
+	raw := make([]byte, 4, 20)
+	binary.BigEndian.PutUint32(raw, uint32(s))
+	raw = append(raw, payload...)
+
+	v := new(big.Int).SetBytes(raw)
+	radix := big.NewInt(int64(len(base62)))
+	digit := new(big.Int)
+
+	var out [27]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		v.DivMod(v, radix, digit)
+		out[i] = base62[digit.Int64()]
+	}
+	return string(out[:])
+}
@@ -0,0 +1,44 @@
+package idgen
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKSUID(t *testing.T) {
+	at := func(s int64) time.Time {
+		return KSUIDEpoch.Add(time.Duration(s)*time.Second + 300*time.Millisecond)
+	}
+	type args struct {
+		readings []time.Time
+		count    int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "payload is incremented within a second",
+			args: args{
+				readings: []time.Time{at(100), at(100), at(101)},
+				count:    3,
+			},
+			want: []string{"0000CZ8VZONcprN0RnGAqthKLUu", "0000CZ8VZONcprN0RnGAqthKLUv", "0000CgvZbbdyvL2mBx3Jdmoa372"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := NewKSUID(clock(tt.args.readings...), fill(0))
+
+			got, err := k.NextN(tt.args.count)
+			if err != nil {
+				t.Fatalf("NextN() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NextN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package idgen
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoBlock is returned when the current block is used up and the next one
+// could not be leased in time.
+var ErrNoBlock = errors.New("no leased block of IDs available")
+
+// BlockSource reserves the next size IDs shared by all nodes and returns
+// the first of them.
+type BlockSource func(size uint64) (uint64, error)
+
+// Lease hands out dense integer IDs from blocks reserved through a shared
+// source. The next block is fetched in the background once half of the
+// current one is used, so the source is rarely on the path of a request and
+// a node that loses the source keeps serving from the blocks it holds.
+// IDs of blocks a node never finishes, e.g. because it crashes, are lost.
+type Lease struct {
+	mu       sync.Mutex
+	source   BlockSource
+	size     uint64
+	retry    time.Duration // pause between failed fetches
+	next     uint64        // next ID of the current block
+	end      uint64        // end of the current block, exclusive
+	spare    []uint64      // first IDs of fetched blocks not used yet
+	fetching bool
+	fetched  chan struct{} // closed when the running fetch succeeds
+}
+
+func NewLease(source BlockSource, size uint64, retry time.Duration) (*Lease, error) {
+	if size == 0 {
+		return nil, errors.New("block size must be positive")
+	}
+	return &Lease{
+		source:  source,
+		size:    size,
+		retry:   retry,
+		spare:   make([]uint64, 0),
+		fetched: make(chan struct{}),
+	}, nil
+}
+
+// Prefetch starts leasing a block unless one is already being leased.
+func (l *Lease) Prefetch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prefetch()
+}
+
+// prefetch must be called with mu held.
+func (l *Lease) prefetch() {
+	if l.fetching {
+		return
+	}
+	l.fetching = true
+	go l.fetch()
+}
+
+// fetch leases a block, retrying until the source answers.
+func (l *Lease) fetch() {
+	for {
+		first, err := l.source(l.size)
+		if err == nil {
+			l.mu.Lock()
+			l.spare = append(l.spare, first)
+			l.fetching = false
+			close(l.fetched)
+			l.fetched = make(chan struct{})
+			l.mu.Unlock()
+			return
+		}
+		time.Sleep(l.retry)
+	}
+}
+
+// Next returns the next ID, waiting at most timeout for a block when none
+// is left.
+func (l *Lease) Next(timeout time.Duration) (uint64, error) {
+	ids, err := l.Take(1, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// Take returns count IDs in increasing order, waiting at most timeout for
+// blocks when the leased ones do not hold enough. IDs are only taken once
+// all count are there, so a Take that times out leaves the blocks intact.
+func (l *Lease) Take(count int, timeout time.Duration) ([]uint64, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		l.mu.Lock()
+		if l.remaining() >= uint64(count) {
+			ids := l.take(count)
+			if len(l.spare) == 0 && l.end-l.next <= l.size/2 {
+				l.prefetch()
+			}
+			l.mu.Unlock()
+			return ids, nil
+		}
+		l.prefetch()
+		fetched := l.fetched
+		l.mu.Unlock()
+
+		select {
+		case <-fetched:
+		case <-deadline.C:
+			return nil, ErrNoBlock
+		}
+	}
+}
+
+// take hands out count IDs, moving on to spare blocks as needed. It must be
+// called with mu held and with at least count IDs remaining.
+func (l *Lease) take(count int) []uint64 {
+	ids := make([]uint64, 0, count)
+	for len(ids) < count {
+		if l.next == l.end {
+			l.next, l.end = l.spare[0], l.spare[0]+l.size
+			l.spare = l.spare[1:]
+		}
+		n := l.end - l.next
+		if want := uint64(count - len(ids)); want < n {
+			n = want
+		}
+		for id := l.next; id < l.next+n; id++ {
+			ids = append(ids, id)
+		}
+		l.next += n
+	}
+	return ids
+}
+
+// Remaining is the number of IDs that can be handed out without the source.
+func (l *Lease) Remaining() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.remaining()
+}
+
+// remaining must be called with mu held.
+func (l *Lease) remaining() uint64 {
+	return l.end - l.next + uint64(len(l.spare))*l.size
+}
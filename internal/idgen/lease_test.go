@@ -0,0 +1,196 @@
+package idgen
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// counterSource reserves blocks from a shared counter and can be cut off
+// like a partitioned lin-kv.
+type counterSource struct {
+	mu   sync.Mutex
+	next uint64
+	down bool
+}
+
+func (c *counterSource) reserve(size uint64) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return 0, errors.New("unreachable")
+	}
+	first := c.next
+	c.next += size
+	return first, nil
+}
+
+func (c *counterSource) setDown(down bool) {
+	c.mu.Lock()
+	c.down = down
+	c.mu.Unlock()
+}
+
+func TestLease(t *testing.T) {
+	type args struct {
+		size  uint64
+		nodes int
+		ids   int // per node, taken in turns
+	}
+	tests := []struct {
+		name string
+		args args
+		want []uint64
+	}{
+		{
+			name: "single node is dense",
+			args: args{size: 4, nodes: 1, ids: 10},
+			want: []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			name: "nodes take disjoint blocks",
+			args: args{size: 3, nodes: 2, ids: 3},
+			want: []uint64{0, 1, 2, 3, 4, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &counterSource{}
+			leases := make([]*Lease, tt.args.nodes)
+			for i := range leases {
+				l, err := NewLease(src.reserve, tt.args.size, time.Millisecond)
+				if err != nil {
+					t.Fatalf("NewLease() error = %v", err)
+				}
+				leases[i] = l
+			}
+
+			seen := make(map[uint64]bool)
+			for i := 0; i < tt.args.ids; i++ {
+				for _, l := range leases {
+					id, err := l.Next(time.Second)
+					if err != nil {
+						t.Fatalf("Next() error = %v", err)
+					}
+					if seen[id] {
+						t.Fatalf("Next() = %d twice", id)
+					}
+					seen[id] = true
+				}
+			}
+
+			got := make([]uint64, 0)
+			for id := uint64(0); id < uint64(len(tt.want)); id++ {
+				if seen[id] {
+					got = append(got, id)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() covered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaseServesDuringPartition(t *testing.T) {
+	src := &counterSource{}
+	l, _ := NewLease(src.reserve, 10, time.Millisecond)
+
+	if id, err := l.Next(time.Second); id != 0 || err != nil {
+		t.Fatalf("Next() = %d, %v, want 0, nil", id, err)
+	}
+	// using half of the block leases the next one in the background
+	for i := 1; i <= 5; i++ {
+		l.Next(time.Second)
+	}
+	deadline := time.Now().Add(time.Second)
+	for l.Remaining() < 14 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := l.Remaining(); got != 14 {
+		t.Fatalf("Remaining() = %d, want 14 after prefetch", got)
+	}
+
+	src.setDown(true)
+	for i := 6; i < 20; i++ {
+		if id, err := l.Next(time.Second); id != uint64(i) || err != nil {
+			t.Fatalf("Next() = %d, %v, want %d, nil", id, err, i)
+		}
+	}
+	if _, err := l.Next(10 * time.Millisecond); !errors.Is(err, ErrNoBlock) {
+		t.Fatalf("Next() error = %v, want ErrNoBlock once leased blocks are used up", err)
+	}
+
+	src.setDown(false)
+	if id, err := l.Next(time.Second); id != 20 || err != nil {
+		t.Errorf("Next() = %d, %v, want 20, nil after the source is back", id, err)
+	}
+}
+
+func TestLeaseTake(t *testing.T) {
+	type args struct {
+		size   uint64
+		counts []int
+	}
+	tests := []struct {
+		name string
+		args args
+		want [][]uint64
+	}{
+		{
+			name: "within a block",
+			args: args{size: 10, counts: []int{3, 2}},
+			want: [][]uint64{{0, 1, 2}, {3, 4}},
+		},
+		{
+			name: "across blocks",
+			args: args{size: 4, counts: []int{3, 7, 1}},
+			want: [][]uint64{{0, 1, 2}, {3, 4, 5, 6, 7, 8, 9}, {10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &counterSource{}
+			l, _ := NewLease(src.reserve, tt.args.size, time.Millisecond)
+
+			got := make([][]uint64, 0)
+			for _, count := range tt.args.counts {
+				ids, err := l.Take(count, time.Second)
+				if err != nil {
+					t.Fatalf("Take(%d) error = %v", count, err)
+				}
+				got = append(got, ids)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Take() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaseTakeTimeoutKeepsBlock(t *testing.T) {
+	src := &counterSource{}
+	l, _ := NewLease(src.reserve, 10, time.Millisecond)
+	if _, err := l.Take(5, time.Second); err != nil {
+		t.Fatalf("Take(5) error = %v", err)
+	}
+	// half of the block is used, so Take leases the next one; let it settle before cutting the source off
+	deadline := time.Now().Add(time.Second)
+	for l.Remaining() < 15 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	src.setDown(true)
+	if _, err := l.Take(20, 10*time.Millisecond); !errors.Is(err, ErrNoBlock) {
+		t.Fatalf("Take(20) error = %v, want ErrNoBlock", err)
+	}
+	if got := l.Remaining(); got != 15 {
+		t.Errorf("Remaining() = %d after a timed out Take, want 15", got)
+	}
+	ids, err := l.Take(3, time.Second)
+	if want := []uint64{5, 6, 7}; err != nil || !reflect.DeepEqual(ids, want) {
+		t.Errorf("Take(3) = %v, %v, want %v, nil", ids, err, want)
+	}
+}
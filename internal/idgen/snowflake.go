@@ -0,0 +1,137 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	NodeBits     = 10
+	SequenceBits = 12
+	MaxNode      = 1<<NodeBits - 1
+	maxSequence  = 1<<SequenceBits - 1
+)
+
+// Epoch is the zero of Snowflake timestamps, which leaves 41 bits of
+// milliseconds for about 69 years after it.
+var Epoch = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrHorizon is returned when issuing an ID would pass the horizon, or
+// would first have to wait more than a second for the clock to reach the
+// millisecond where IDs continue.
+var ErrHorizon = errors.New("clock is outside the window IDs may be issued in")
+
+// maxWait bounds how far behind the clock may be before Next gives up
+// instead of waiting for it.
+const maxWait = time.Second
+
+// Snowflake packs milliseconds since Epoch, a node index and a sequence
+// number within the millisecond into a 64-bit ID, so IDs sort by time and
+// never clash between nodes.
+//
+// The millisecond of an ID is the clock reading or, when the clock went
+// backwards, the millisecond of the previous ID, whose sequence keeps
+// counting. So IDs of a running generator never repeat, but they may
+// run ahead of the clock by as much as it went back. When the sequence of a
+// millisecond is used up, Next waits for the clock to pass it.
+//
+// A fresh generator knows nothing of IDs issued before a restart. Resume
+// and Extend close that gap: IDs stay below a horizon the caller persisted
+// beforehand, and a restarted node resumes at the persisted horizon.
+type Snowflake struct {
+	mu      sync.Mutex
+	node    uint64
+	now     func() time.Time
+	last    int64 // millisecond of the last ID
+	seq     uint64
+	horizon int64 // first millisecond IDs may not use yet
+}
+
+func NewSnowflake(node int, now func() time.Time) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("node index %d out of range 0..%d", node, MaxNode)
+	}
+	return &Snowflake{node: uint64(node), now: now, last: -1, horizon: math.MaxInt64}, nil
+}
+
+// Resume makes IDs continue at millisecond from or later, the horizon of
+// an earlier run.
+func (s *Snowflake) Resume(from int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if from-1 > s.last {
+		s.last = from - 1
+		s.seq = maxSequence
+	}
+}
+
+// Extend lets IDs use milliseconds before horizon. The horizon never moves
+// back, since IDs below it may have been issued already.
+func (s *Snowflake) Extend(horizon int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if horizon > s.horizon || s.horizon == math.MaxInt64 {
+		s.horizon = horizon
+	}
+}
+
+func (s *Snowflake) millis() int64 {
+	return s.now().Sub(Epoch).Milliseconds()
+}
+
+func (s *Snowflake) Next() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next()
+}
+
+// NextN returns count consecutive IDs, with no other ID of this generator
+// in between.
+func (s *Snowflake) NextN(count int) ([]uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]uint64, count)
+	for i := range ids {
+		id, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// next must be called with mu held.
+func (s *Snowflake) next() (uint64, error) {
+	ms := s.millis()
+	if ms <= s.last && s.seq < maxSequence {
+		s.seq++
+	} else {
+		if time.Duration(s.last-ms)*time.Millisecond > maxWait {
+			return 0, ErrHorizon
+		}
+		for ms <= s.last {
+			time.Sleep(100 * time.Microsecond)
+			ms = s.millis()
+		}
+		if ms >= s.horizon {
+			return 0, ErrHorizon
+		}
+		s.last = ms
+		s.seq = 0
+	}
+
+	return uint64(s.last)<<(NodeBits+SequenceBits) | s.node<<SequenceBits | s.seq, nil
+}
+
+// Decompose splits a Snowflake ID into its time, node and sequence.
+func Decompose(id uint64) (time.Time, int, int) {
+	ms := int64(id >> (NodeBits + SequenceBits))
+	node := int(id >> SequenceBits & MaxNode)
+	seq := int(id & maxSequence)
+	return Epoch.Add(time.Duration(ms) * time.Millisecond), node, seq
+}
@@ -0,0 +1,209 @@
+package idgen
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnowflake(t *testing.T) {
+	type args struct {
+		node  int
+		ticks []time.Duration // clock reading after Epoch for every ID
+	}
+	type part struct {
+		ms   int64
+		node int
+		seq  int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []part
+	}{
+		{
+			name: "sequence restarts every millisecond",
+			args: args{
+				node:  3,
+				ticks: []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, 6 * time.Millisecond},
+			},
+			want: []part{{5, 3, 0}, {5, 3, 1}, {6, 3, 0}},
+		},
+		{
+			name: "clock going backwards keeps counting in the last millisecond",
+			args: args{
+				node:  1,
+				ticks: []time.Duration{10 * time.Millisecond, 7 * time.Millisecond, 8 * time.Millisecond, 11 * time.Millisecond},
+			},
+			want: []part{{10, 1, 0}, {10, 1, 1}, {10, 1, 2}, {11, 1, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := 0
+			s, err := NewSnowflake(tt.args.node, func() time.Time {
+				tick := tt.args.ticks[i]
+				i++
+				return Epoch.Add(tick)
+			})
+			if err != nil {
+				t.Fatalf("NewSnowflake() error = %v", err)
+			}
+
+			got := make([]part, 0)
+			var prev uint64
+			for range tt.args.ticks {
+				id, err := s.Next()
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				if id <= prev {
+					t.Errorf("Next() = %d after %d, want increasing IDs", id, prev)
+				}
+				prev = id
+				at, node, seq := Decompose(id)
+				got = append(got, part{at.Sub(Epoch).Milliseconds(), node, seq})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnowflakeOverflow(t *testing.T) {
+	// the clock moves one millisecond every 4200 readings
+	calls := 0
+	s, _ := NewSnowflake(MaxNode, func() time.Time {
+		calls++
+		return Epoch.Add(time.Second + time.Duration(calls/4200)*time.Millisecond)
+	})
+
+	seen := make(map[uint64]bool)
+	var prev uint64
+	for i := 0; i < maxSequence+2; i++ {
+		id, err := s.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if seen[id] || id <= prev {
+			t.Fatalf("Next() = %d after %d, want fresh increasing IDs", id, prev)
+		}
+		seen[id] = true
+		prev = id
+	}
+
+	// the last ID had to wait for the next millisecond
+	at, node, seq := Decompose(prev)
+	if got := at.Sub(Epoch); got != time.Second+time.Millisecond || node != MaxNode || seq != 0 {
+		t.Errorf("Decompose() = %v, %d, %d, want 1.001s, %d, 0", got, node, seq, MaxNode)
+	}
+	if calls < 4200 {
+		t.Errorf("clock read %d times, want Next to wait for it", calls)
+	}
+}
+
+func TestNewSnowflakeRejectsNode(t *testing.T) {
+	if _, err := NewSnowflake(MaxNode+1, time.Now); err == nil {
+		t.Errorf("NewSnowflake(%d) error = nil, want out of range", MaxNode+1)
+	}
+}
+
+func TestSnowflakeNextN(t *testing.T) {
+	s, _ := NewSnowflake(2, func() time.Time {
+		return Epoch.Add(time.Minute)
+	})
+
+	s.Next()
+	ids, err := s.NextN(3)
+	if err != nil {
+		t.Fatalf("NextN() error = %v", err)
+	}
+	got := make([]int, 0)
+	for _, id := range ids {
+		_, _, seq := Decompose(id)
+		got = append(got, seq)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NextN() sequences = %v, want %v", got, want)
+	}
+}
+
+func TestSnowflakeHorizon(t *testing.T) {
+	type args struct {
+		resume  int64 // horizon of the previous run, 0 for none
+		horizon int64 // horizon of this run, 0 for none
+		tick    time.Duration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantMs  int64
+		wantErr error
+	}{
+		{
+			name:   "fresh generator follows the clock",
+			args:   args{tick: 50 * time.Millisecond},
+			wantMs: 50,
+		},
+		{
+			name:   "restart after the previous horizon follows the clock",
+			args:   args{resume: 40, horizon: 1050, tick: 50 * time.Millisecond},
+			wantMs: 50,
+		},
+		{
+			name:   "restart with the clock behind continues at the previous horizon",
+			args:   args{resume: 40, horizon: 1040, tick: 39 * time.Millisecond},
+			wantMs: 40,
+		},
+		{
+			name:    "clock far behind the previous horizon",
+			args:    args{resume: 5000, horizon: 6000, tick: 50 * time.Millisecond},
+			wantErr: ErrHorizon,
+		},
+		{
+			name:    "clock past the horizon",
+			args:    args{horizon: 50, tick: 50 * time.Millisecond},
+			wantErr: ErrHorizon,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the clock moves on when Next has to wait for it
+			tick := tt.args.tick
+			s, _ := NewSnowflake(1, func() time.Time {
+				tick += 100 * time.Microsecond
+				return Epoch.Add(tick)
+			})
+			if tt.args.resume > 0 {
+				s.Resume(tt.args.resume)
+			}
+			if tt.args.horizon > 0 {
+				s.Extend(tt.args.horizon)
+			}
+
+			id, err := s.Next()
+			if err != tt.wantErr {
+				t.Fatalf("Next() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			at, _, _ := Decompose(id)
+			if got := at.Sub(Epoch).Milliseconds(); got != tt.wantMs {
+				t.Errorf("Next() at %dms, want %dms", got, tt.wantMs)
+			}
+		})
+	}
+}
+
+func TestSnowflakeExtendNeverShrinks(t *testing.T) {
+	s, _ := NewSnowflake(1, func() time.Time {
+		return Epoch.Add(50 * time.Millisecond)
+	})
+	s.Extend(100)
+	s.Extend(10)
+	if _, err := s.Next(); err != nil {
+		t.Errorf("Next() error = %v, want the horizon to stay at 100", err)
+	}
+}
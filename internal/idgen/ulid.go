@@ -0,0 +1,121 @@
+package idgen
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// monotonic draws a timestamp and a random payload for every ID. Within one
+// tick of the clock the payload of the previous ID is incremented instead of
+// drawn again, so the IDs of one generator increase even when the clock
+// stands still or goes backwards. Should the payload overflow, the next ID
+// waits for the next tick.
+type monotonic struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	random  io.Reader
+	epoch   time.Time
+	tick    time.Duration
+	last    int64 // ticks since epoch of the last ID
+	payload []byte
+}
+
+func newMonotonic(now func() time.Time, random io.Reader, epoch time.Time, tick time.Duration, size int) *monotonic {
+	return &monotonic{now: now, random: random, epoch: epoch, tick: tick, last: -1, payload: make([]byte, size)}
+}
+
+func (m *monotonic) ticks() int64 {
+	return int64(m.now().Sub(m.epoch) / m.tick)
+}
+
+// next returns the timestamp and payload of the next ID; the payload is
+// only valid until the next call. Must be called with mu held.
+func (m *monotonic) next() (int64, []byte, error) {
+	ts := m.ticks()
+	if ts <= m.last {
+		if increment(m.payload) {
+			return m.last, m.payload, nil
+		}
+		for ts <= m.last {
+			time.Sleep(100 * time.Microsecond)
+			ts = m.ticks()
+		}
+	}
+	if _, err := io.ReadFull(m.random, m.payload); err != nil {
+		return 0, nil, err
+	}
+	m.last = ts
+	return ts, m.payload, nil
+}
+
+// nextN encodes count IDs, with no other ID of this generator in between.
+func (m *monotonic) nextN(count int, encode func(ts int64, payload []byte) string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, count)
+	for i := range ids {
+		ts, payload, err := m.next()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = encode(ts, payload)
+	}
+	return ids, nil
+}
+
+// increment adds one to a big-endian number and reports false when it
+// wrapped around to zero.
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ULID issues 26 character IDs in Crockford base32: 48 bits of Unix
+// milliseconds followed by 80 random bits. They sort lexicographically by
+// time, and by issue order within a millisecond.
+type ULID struct {
+	m *monotonic
+}
+
+func NewULID(now func() time.Time, random io.Reader) *ULID {
+	return &ULID{m: newMonotonic(now, random, time.Unix(0, 0), time.Millisecond, 10)}
+}
+
+func (u *ULID) Next() (string, error) {
+	ids, err := u.NextN(1)
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+func (u *ULID) NextN(count int) ([]string, error) {
+	return u.m.nextN(count, encodeULID)
+}
+
+// encodeULID writes the 128 bits of timestamp and entropy five bits at a
+// time from the end; the first character holds the top three bits.
+func encodeULID(ms int64, entropy []byte) string {
+	hi := uint64(ms)<<16 | uint64(entropy[0])<<8 | uint64(entropy[1])
+	var lo uint64
+	for _, b := range entropy[2:] {
+		lo = lo<<8 | uint64(b)
+	}
+
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
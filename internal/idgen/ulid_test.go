@@ -0,0 +1,102 @@
+package idgen
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fill is a random source that returns the same byte over and over.
+type fill byte
+
+func (f fill) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(f)
+	}
+	return len(p), nil
+}
+
+// clock returns the given readings in turn.
+func clock(readings ...time.Time) func() time.Time {
+	i := 0
+	return func() time.Time {
+		r := readings[i]
+		i++
+		return r
+	}
+}
+
+func TestULID(t *testing.T) {
+	ms := func(ms int64) time.Time {
+		return time.UnixMilli(ms)
+	}
+	type args struct {
+		random   fill
+		readings []time.Time
+		count    int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "entropy is incremented within a millisecond",
+			args: args{
+				readings: []time.Time{ms(1469918176385), ms(1469918176385), ms(1469918176386)},
+				count:    3,
+			},
+			want: []string{"01ARYZ6S410000000000000000", "01ARYZ6S410000000000000001", "01ARYZ6S420000000000000000"},
+		},
+		{
+			name: "clock going backwards keeps the last millisecond",
+			args: args{
+				readings: []time.Time{ms(1469918176385), ms(1469918176384)},
+				count:    2,
+			},
+			want: []string{"01ARYZ6S410000000000000000", "01ARYZ6S410000000000000001"},
+		},
+		{
+			name: "exhausted entropy waits for the next millisecond",
+			args: args{
+				random:   0xff,
+				readings: []time.Time{ms(1469918176385), ms(1469918176385), ms(1469918176386)},
+				count:    2,
+			},
+			want: []string{"01ARYZ6S41ZZZZZZZZZZZZZZZZ", "01ARYZ6S42ZZZZZZZZZZZZZZZZ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewULID(clock(tt.args.readings...), tt.args.random)
+
+			got := make([]string, 0)
+			for i := 0; i < tt.args.count; i++ {
+				id, err := u.Next()
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestULIDNextNSorts(t *testing.T) {
+	u := NewULID(func() time.Time {
+		return time.UnixMilli(1469918176385)
+	}, fill(0xfe))
+
+	ids, err := u.NextN(300)
+	if err != nil {
+		t.Fatalf("NextN() error = %v", err)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("NextN() = %s after %s, want lexicographically increasing IDs", ids[i], ids[i-1])
+		}
+	}
+}
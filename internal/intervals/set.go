@@ -0,0 +1,192 @@
+package intervals
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Range is an inclusive interval of integers. It serializes to a compact
+// two-element JSON array.
+type Range [2]int
+
+// Set stores integers as sorted, non-adjacent ranges, so dense values take
+// constant memory. The ranges live in a treap keyed by their start, so
+// lookups and inserts take O(log n) for n ranges, including inserts that
+// open a new gap.
+type Set struct {
+	root   *node
+	ranges int
+	count  int
+	seed   uint64 // state of the priority generator
+}
+
+func New() *Set {
+	return &Set{seed: 0x9e3779b97f4a7c15}
+}
+
+func FromRanges(ranges []Range) *Set {
+	s := New()
+	for _, r := range normalize(ranges) {
+		s.insert(r)
+		s.count += r[1] - r[0] + 1
+	}
+	return s
+}
+
+// priority draws the next treap priority with xorshift.
+func (s *Set) priority() uint64 {
+	s.seed ^= s.seed << 13
+	s.seed ^= s.seed >> 7
+	s.seed ^= s.seed << 17
+	return s.seed
+}
+
+func (s *Set) insert(r Range) {
+	s.root = insert(s.root, &node{r: r, prio: s.priority()})
+	s.ranges++
+}
+
+func (s *Set) Contains(v int) bool {
+	prev := floor(s.root, v)
+	return prev != nil && prev.r[1] >= v
+}
+
+// Add inserts v and reports whether it was not in the set before.
+func (s *Set) Add(v int) bool {
+	prev := floor(s.root, v)
+	if prev != nil && prev.r[1] >= v {
+		return false
+	}
+	next := ceiling(s.root, v)
+	s.count++
+
+	extendsPrev := prev != nil && prev.r[1] == v-1
+	extendsNext := next != nil && next.r[0] == v+1
+	switch {
+	case extendsPrev && extendsNext:
+		prev.r[1] = next.r[1]
+		s.root = remove(s.root, next.r[0])
+		s.ranges--
+	case extendsPrev:
+		prev.r[1] = v
+	case extendsNext:
+		// no other range starts between v and v+1, so the order holds
+		next.r[0] = v
+	default:
+		s.insert(Range{v, v})
+	}
+	return true
+}
+
+// Len returns the number of values in the set.
+func (s *Set) Len() int {
+	return s.count
+}
+
+// Ranges returns a copy of the set's ranges in ascending order.
+func (s *Set) Ranges() []Range {
+	ranges := make([]Range, 0, s.ranges)
+	walk(s.root, func(r Range) {
+		ranges = append(ranges, r)
+	})
+	return ranges
+}
+
+// Values returns every value in ascending order.
+func (s *Set) Values() []int {
+	return expand(s.Ranges(), s.count)
+}
+
+// Except returns the smallest values of the set that ranges do not cover,
+// at most limit of them.
+func (s *Set) Except(ranges []Range, limit int) []int {
+	return expand(subtract(s.Ranges(), normalize(ranges)), limit)
+}
+
+// Gaps returns the smallest values covered by ranges that the set does not
+// contain, at most limit of them. A digest covering a huge span therefore
+// costs no more than limit values.
+func (s *Set) Gaps(ranges []Range, limit int) []int {
+	return expand(subtract(normalize(ranges), s.Ranges()), limit)
+}
+
+// Validate rejects ranges that end before they start, which normalize would
+// otherwise silently drop.
+func Validate(ranges []Range) error {
+	for _, r := range ranges {
+		if r[0] > r[1] {
+			return fmt.Errorf("malformed range %v: ends before it starts", r)
+		}
+	}
+	return nil
+}
+
+// normalize sorts ranges and merges the overlapping or adjacent ones.
+func normalize(ranges []Range) []Range {
+	sorted := make([]Range, 0, len(ranges))
+	for _, r := range ranges {
+		if r[0] <= r[1] {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+
+	merged := make([]Range, 0, len(sorted))
+	for _, r := range sorted {
+		last := len(merged) - 1
+		// comparing r[0]-1 instead of end+1 keeps math.MaxInt from wrapping
+		if last >= 0 && (r[0] <= merged[last][1] || r[0]-1 == merged[last][1]) {
+			if r[1] > merged[last][1] {
+				merged[last][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// subtract returns the parts of a not covered by b. Both must be normalized.
+func subtract(a, b []Range) []Range {
+	diff := make([]Range, 0)
+	j := 0
+	for _, r := range a {
+		lo, covered := r[0], false
+		for j < len(b) && b[j][1] < lo {
+			j++
+		}
+		for k := j; k < len(b) && b[k][0] <= r[1]; k++ {
+			if b[k][0] > lo {
+				diff = append(diff, Range{lo, b[k][0] - 1})
+			}
+			if b[k][1] >= r[1] {
+				covered = true
+				break
+			}
+			lo = b[k][1] + 1
+		}
+		if !covered {
+			diff = append(diff, Range{lo, r[1]})
+		}
+	}
+	return diff
+}
+
+// expand lists the values of ranges in ascending order, at most limit of
+// them. The loop stops at the end of a range rather than past it, so a range
+// ending at math.MaxInt terminates.
+func expand(ranges []Range, limit int) []int {
+	values := make([]int, 0)
+	for _, r := range ranges {
+		for v := r[0]; len(values) < limit; v++ {
+			values = append(values, v)
+			if v == r[1] {
+				break
+			}
+		}
+		if len(values) >= limit {
+			break
+		}
+	}
+	return values
+}
@@ -0,0 +1,208 @@
+package intervals
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSetAdd(t *testing.T) {
+	type args struct {
+		values []int
+	}
+	tests := []struct {
+		name   string
+		args   args
+		want   []Range
+		length int
+	}{
+		{
+			name: "dense values collapse into one range",
+			args: args{
+				values: []int{3, 1, 0, 2, 4},
+			},
+			want:   []Range{{0, 4}},
+			length: 5,
+		},
+		{
+			name: "value between two ranges merges them",
+			args: args{
+				values: []int{0, 1, 5, 6, 3, 2, 4},
+			},
+			want:   []Range{{0, 6}},
+			length: 7,
+		},
+		{
+			name: "sparse values keep separate ranges",
+			args: args{
+				values: []int{10, 0, 5, 11, 5},
+			},
+			want:   []Range{{0, 0}, {5, 5}, {10, 11}},
+			length: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for _, v := range tt.args.values {
+				s.Add(v)
+			}
+			if got := s.Ranges(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ranges() = %v, want %v", got, tt.want)
+			}
+			if got := s.Len(); got != tt.length {
+				t.Errorf("Len() = %v, want %v", got, tt.length)
+			}
+		})
+	}
+}
+
+func TestSetContains(t *testing.T) {
+	s := FromRanges([]Range{{0, 4}, {10, 12}})
+	for v, want := range map[int]bool{-1: false, 0: true, 4: true, 5: false, 9: false, 11: true, 13: false} {
+		if got := s.Contains(v); got != want {
+			t.Errorf("Contains(%d) = %v, want %v", v, got, want)
+		}
+	}
+	if s.Add(11) {
+		t.Errorf("Add(11) reported a new value")
+	}
+	if !s.Add(5) {
+		t.Errorf("Add(5) reported a known value")
+	}
+}
+
+func TestSetDiff(t *testing.T) {
+	type args struct {
+		set    []Range
+		digest []Range
+		limit  int
+	}
+	tests := []struct {
+		name   string
+		args   args
+		except []int
+		gaps   []int
+	}{
+		{
+			name: "equal sets",
+			args: args{
+				set:    []Range{{0, 9}},
+				digest: []Range{{0, 9}},
+				limit:  100,
+			},
+			except: []int{},
+			gaps:   []int{},
+		},
+		{
+			name: "overlapping sets",
+			args: args{
+				set:    []Range{{0, 3}, {8, 9}},
+				digest: []Range{{2, 5}, {9, 9}},
+				limit:  100,
+			},
+			except: []int{0, 1, 8},
+			gaps:   []int{4, 5},
+		},
+		{
+			name: "unsorted digest",
+			args: args{
+				set:    []Range{{0, 5}},
+				digest: []Range{{4, 6}, {0, 1}, {1, 2}},
+				limit:  100,
+			},
+			except: []int{3},
+			gaps:   []int{6},
+		},
+		{
+			name: "limit keeps the smallest values",
+			args: args{
+				set:    []Range{{0, 9}},
+				digest: []Range{{5, 20}},
+				limit:  3,
+			},
+			except: []int{0, 1, 2},
+			gaps:   []int{10, 11, 12},
+		},
+		{
+			name: "digest reaching the largest int",
+			args: args{
+				set:    []Range{{math.MaxInt - 2, math.MaxInt}},
+				digest: []Range{{0, math.MaxInt}, {math.MaxInt, math.MaxInt}},
+				limit:  4,
+			},
+			except: []int{},
+			gaps:   []int{0, 1, 2, 3},
+		},
+		{
+			name: "set reaching the largest int",
+			args: args{
+				set:    []Range{{math.MaxInt - 2, math.MaxInt}},
+				digest: []Range{{math.MaxInt - 1, math.MaxInt}},
+				limit:  4,
+			},
+			except: []int{math.MaxInt - 2},
+			gaps:   []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := FromRanges(tt.args.set)
+			if got := s.Except(tt.args.digest, tt.args.limit); !reflect.DeepEqual(got, tt.except) {
+				t.Errorf("Except() = %v, want %v", got, tt.except)
+			}
+			if got := s.Gaps(tt.args.digest, tt.args.limit); !reflect.DeepEqual(got, tt.gaps) {
+				t.Errorf("Gaps() = %v, want %v", got, tt.gaps)
+			}
+		})
+	}
+}
+
+func TestSetAddMatchesReference(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := New()
+	ref := make(map[int]bool)
+	for i := 0; i < 20000; i++ {
+		v := rng.Intn(30000)
+		if got, want := s.Add(v), !ref[v]; got != want {
+			t.Fatalf("Add(%d) = %v, want %v", v, got, want)
+		}
+		ref[v] = true
+	}
+
+	want := make([]int, 0, len(ref))
+	for v := range ref {
+		want = append(want, v)
+	}
+	sort.Ints(want)
+	if got := s.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() differ from the inserted values")
+	}
+	if got := FromRanges(s.Ranges()).Ranges(); !reflect.DeepEqual(got, s.Ranges()) {
+		t.Errorf("Ranges() are not sorted and disjoint: %v", got)
+	}
+	if s.Len() != len(ref) {
+		t.Errorf("Len() = %d, want %d", s.Len(), len(ref))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranges  []Range
+		wantErr bool
+	}{
+		{name: "empty", ranges: []Range{}},
+		{name: "single values and spans", ranges: []Range{{3, 3}, {0, math.MaxInt}}},
+		{name: "reversed range", ranges: []Range{{0, 1}, {5, 4}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.ranges); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package intervals
+
+// node is a range in a treap ordered by range start. Priorities are random,
+// which keeps the tree balanced in expectation, so every operation below
+// takes O(log n) for n ranges.
+type node struct {
+	r           Range
+	prio        uint64
+	left, right *node
+}
+
+// floor returns the range with the largest start not after v.
+func floor(t *node, v int) *node {
+	var best *node
+	for t != nil {
+		if t.r[0] <= v {
+			best = t
+			t = t.right
+		} else {
+			t = t.left
+		}
+	}
+	return best
+}
+
+// ceiling returns the range with the smallest start after v.
+func ceiling(t *node, v int) *node {
+	var best *node
+	for t != nil {
+		if t.r[0] > v {
+			best = t
+			t = t.left
+		} else {
+			t = t.right
+		}
+	}
+	return best
+}
+
+// split divides t into the ranges starting before start and the rest.
+func split(t *node, start int) (*node, *node) {
+	if t == nil {
+		return nil, nil
+	}
+	if t.r[0] < start {
+		l, r := split(t.right, start)
+		t.right = l
+		return t, r
+	}
+	l, r := split(t.left, start)
+	t.left = r
+	return l, t
+}
+
+// merge joins two treaps where every start in l is before every start in r.
+func merge(l, r *node) *node {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	case l.prio > r.prio:
+		l.right = merge(l.right, r)
+		return l
+	default:
+		r.left = merge(l, r.left)
+		return r
+	}
+}
+
+func insert(t *node, n *node) *node {
+	l, r := split(t, n.r[0])
+	return merge(merge(l, n), r)
+}
+
+// remove drops the range starting at start.
+func remove(t *node, start int) *node {
+	if t == nil {
+		return nil
+	}
+	switch {
+	case start < t.r[0]:
+		t.left = remove(t.left, start)
+	case start > t.r[0]:
+		t.right = remove(t.right, start)
+	default:
+		return merge(t.left, t.right)
+	}
+	return t
+}
+
+// walk calls fn for every range in ascending order.
+func walk(t *node, fn func(r Range)) {
+	for t != nil {
+		walk(t.left, fn)
+		fn(t.r)
+		t = t.right
+	}
+}
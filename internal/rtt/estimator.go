@@ -0,0 +1,71 @@
+package rtt
+
+import "time"
+
+// Estimator computes a retransmission timeout from round-trip samples the
+// way TCP does (RFC 6298): RTO = SRTT + 4*RTTVAR, doubled on every timeout
+// until a fresh sample arrives.
+type Estimator struct {
+	srtt    time.Duration
+	rttvar  time.Duration
+	rto     time.Duration
+	backoff uint
+	sampled bool
+	min     time.Duration
+	max     time.Duration
+}
+
+func NewEstimator(initial, min, max time.Duration) *Estimator {
+	return &Estimator{
+		rto: initial,
+		min: min,
+		max: max,
+	}
+}
+
+// Observe records a round-trip sample and resets the backoff. Samples from
+// retransmitted requests are ambiguous and should not be observed (Karn's
+// algorithm).
+func (e *Estimator) Observe(sample time.Duration) {
+	if !e.sampled {
+		e.srtt = sample
+		e.rttvar = sample / 2
+		e.sampled = true
+	} else {
+		delta := e.srtt - sample
+		if delta < 0 {
+			delta = -delta
+		}
+		e.rttvar = (3*e.rttvar + delta) / 4
+		e.srtt = (7*e.srtt + sample) / 8
+	}
+	e.rto = e.srtt + 4*e.rttvar
+	e.backoff = 0
+}
+
+// Timeout doubles the timeout after a retransmission.
+func (e *Estimator) Timeout() {
+	if e.RTO() < e.max {
+		e.backoff++
+	}
+}
+
+// RTO returns the current retransmission timeout including backoff.
+func (e *Estimator) RTO() time.Duration {
+	rto := e.rto
+	if rto < e.min {
+		rto = e.min
+	}
+	for i := uint(0); i < e.backoff && rto < e.max; i++ {
+		rto *= 2
+	}
+	if rto > e.max {
+		rto = e.max
+	}
+	return rto
+}
+
+// SRTT returns the smoothed round-trip time, or zero before the first sample.
+func (e *Estimator) SRTT() time.Duration {
+	return e.srtt
+}
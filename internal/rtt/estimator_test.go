@@ -0,0 +1,75 @@
+package rtt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimator(t *testing.T) {
+	type args struct {
+		samples  []time.Duration
+		timeouts int
+	}
+	tests := []struct {
+		name string
+		args args
+		want time.Duration
+	}{
+		{
+			name: "no samples keep initial timeout",
+			args: args{},
+			want: time.Second,
+		},
+		{
+			name: "first sample sets rto to three round trips",
+			args: args{
+				samples: []time.Duration{200 * time.Millisecond},
+			},
+			want: 600 * time.Millisecond,
+		},
+		{
+			name: "stable samples shrink variance",
+			args: args{
+				samples: []time.Duration{200 * time.Millisecond, 200 * time.Millisecond},
+			},
+			want: 500 * time.Millisecond,
+		},
+		{
+			name: "timeouts back off exponentially",
+			args: args{
+				samples:  []time.Duration{200 * time.Millisecond},
+				timeouts: 2,
+			},
+			want: 2400 * time.Millisecond,
+		},
+		{
+			name: "backoff is capped",
+			args: args{
+				samples:  []time.Duration{200 * time.Millisecond},
+				timeouts: 10,
+			},
+			want: 5 * time.Second,
+		},
+		{
+			name: "tiny round trips respect minimum",
+			args: args{
+				samples: []time.Duration{time.Millisecond},
+			},
+			want: 50 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEstimator(time.Second, 50*time.Millisecond, 5*time.Second)
+			for _, s := range tt.args.samples {
+				e.Observe(s)
+			}
+			for i := 0; i < tt.args.timeouts; i++ {
+				e.Timeout()
+			}
+			if got := e.RTO(); got != tt.want {
+				t.Errorf("RTO() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package topology
+
+import (
+	"math"
+	"sort"
+)
+
+// Metrics summarizes how well a topology spreads messages. Paths follow
+// the direction of edges, since a node only sends to its own neighbors.
+type Metrics struct {
+	Nodes        int
+	Edges        int // directed edges, an undirected link counts twice
+	Diameter     int
+	AvgPath      float64
+	MinDegree    int
+	MaxDegree    int
+	Symmetric    bool
+	Connected    bool
+	Connectivity int
+}
+
+func Analyze(topology map[string][]string) Metrics {
+	g := newDigraph(topology)
+	edges := 0
+	for _, out := range g.out {
+		edges += len(out)
+	}
+	minDegree, maxDegree := Degree(topology)
+	return Metrics{
+		Nodes:        len(g.names),
+		Edges:        edges,
+		Diameter:     Diameter(topology),
+		AvgPath:      AveragePathLength(topology),
+		MinDegree:    minDegree,
+		MaxDegree:    maxDegree,
+		Symmetric:    Symmetric(topology),
+		Connected:    Connected(topology),
+		Connectivity: VertexConnectivity(topology),
+	}
+}
+
+// Diameter returns the longest shortest path in hops, which bounds how many
+// gossip rounds a value needs to reach every node. It returns -1 when some
+// node cannot reach another.
+func Diameter(topology map[string][]string) int {
+	g := newDigraph(topology)
+	diameter := 0
+	for src := range g.out {
+		for _, d := range g.distances(src) {
+			if d < 0 {
+				return -1
+			}
+			if d > diameter {
+				diameter = d
+			}
+		}
+	}
+	return diameter
+}
+
+// AveragePathLength returns the mean shortest path in hops over all pairs
+// of distinct nodes, or +Inf when some node cannot reach another.
+func AveragePathLength(topology map[string][]string) float64 {
+	g := newDigraph(topology)
+	if len(g.out) < 2 {
+		return 0
+	}
+	total := 0
+	for src := range g.out {
+		for _, d := range g.distances(src) {
+			if d < 0 {
+				return math.Inf(1)
+			}
+			total += d
+		}
+	}
+	pairs := len(g.out) * (len(g.out) - 1)
+	return float64(total) / float64(pairs)
+}
+
+// Degree returns the smallest and the largest number of neighbors a node
+// sends to.
+func Degree(topology map[string][]string) (int, int) {
+	g := newDigraph(topology)
+	if len(g.out) == 0 {
+		return 0, 0
+	}
+	smallest, largest := len(g.out[0]), len(g.out[0])
+	for _, out := range g.out {
+		if len(out) < smallest {
+			smallest = len(out)
+		}
+		if len(out) > largest {
+			largest = len(out)
+		}
+	}
+	return smallest, largest
+}
+
+// Symmetric reports whether every node is a neighbor of its neighbors.
+func Symmetric(topology map[string][]string) bool {
+	g := newDigraph(topology)
+	for a, out := range g.out {
+		for _, b := range out {
+			if !g.has(b, a) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Connected reports whether every node can reach every other node.
+func Connected(topology map[string][]string) bool {
+	return Diameter(topology) >= 0
+}
+
+// VertexConnectivity returns the smallest number of nodes whose failure
+// leaves some of the remaining nodes unable to reach each other. A graph
+// in which every node sends to every other node survives all but one.
+func VertexConnectivity(topology map[string][]string) int {
+	g := newDigraph(topology)
+	if len(g.out) < 2 {
+		return 0
+	}
+	connectivity := len(g.out) - 1
+	for s := range g.out {
+		for t := range g.out {
+			if s == t || g.has(s, t) {
+				continue
+			}
+			if paths := g.disjointPaths(s, t, connectivity); paths < connectivity {
+				connectivity = paths
+			}
+		}
+	}
+	return connectivity
+}
+
+// digraph indexes a topology. Nodes that only appear as neighbors are
+// included, self-loops and repeated neighbors are dropped.
+type digraph struct {
+	names []string
+	out   [][]int
+}
+
+func newDigraph(topology map[string][]string) digraph {
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(topology))
+	for n, nbrs := range topology {
+		for _, name := range append([]string{n}, nbrs...) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	index := make(map[string]int, len(names))
+	for i, n := range names {
+		index[n] = i
+	}
+
+	out := make([][]int, len(names))
+	for n, nbrs := range topology {
+		src := index[n]
+		linked := make(map[int]bool)
+		for _, nbr := range nbrs {
+			dst := index[nbr]
+			if dst != src && !linked[dst] {
+				linked[dst] = true
+				out[src] = append(out[src], dst)
+			}
+		}
+	}
+
+	return digraph{names: names, out: out}
+}
+
+func (g digraph) has(a, b int) bool {
+	for _, n := range g.out[a] {
+		if n == b {
+			return true
+		}
+	}
+	return false
+}
+
+// distances returns hops from src to every other node, -1 if unreachable.
+func (g digraph) distances(src int) []int {
+	dist := make([]int, len(g.out))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[src] = 0
+	queue := []int{src}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range g.out[cur] {
+			if dist[next] < 0 {
+				dist[next] = dist[cur] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	others := make([]int, 0, len(dist)-1)
+	for i, d := range dist {
+		if i != src {
+			others = append(others, d)
+		}
+	}
+	return others
+}
+
+// disjointPaths counts paths from s to t that share no intermediate node,
+// stopping once limit paths are found. Every node is split into an inbound
+// and an outbound half joined by an edge of capacity one, which turns the
+// count into a max flow.
+func (g digraph) disjointPaths(s, t int, limit int) int {
+	type edge struct {
+		to       int
+		capacity int
+		rev      int
+	}
+	residual := make([][]edge, 2*len(g.out))
+	connect := func(from, to, capacity int) {
+		residual[from] = append(residual[from], edge{to: to, capacity: capacity, rev: len(residual[to])})
+		residual[to] = append(residual[to], edge{to: from, capacity: 0, rev: len(residual[from]) - 1})
+	}
+	in := func(v int) int { return 2 * v }
+	out := func(v int) int { return 2*v + 1 }
+
+	for v := range g.out {
+		capacity := 1
+		if v == s || v == t {
+			capacity = len(g.out)
+		}
+		connect(in(v), out(v), capacity)
+		for _, w := range g.out[v] {
+			connect(out(v), in(w), 1)
+		}
+	}
+
+	flow := 0
+	for flow < limit {
+		type hop struct{ node, edge int }
+		prev := make([]hop, len(residual))
+		for i := range prev {
+			prev[i] = hop{-1, -1}
+		}
+		prev[out(s)] = hop{out(s), -1}
+		queue := []int{out(s)}
+		for len(queue) > 0 && prev[in(t)].node < 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for i, e := range residual[cur] {
+				if e.capacity > 0 && prev[e.to].node < 0 {
+					prev[e.to] = hop{cur, i}
+					queue = append(queue, e.to)
+				}
+			}
+		}
+		if prev[in(t)].node < 0 {
+			break
+		}
+
+		for v := in(t); v != out(s); v = prev[v].node {
+			e := &residual[prev[v].node][prev[v].edge]
+			e.capacity--
+			residual[v][e.rev].capacity++
+		}
+		flow++
+	}
+	return flow
+}
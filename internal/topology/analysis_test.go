@@ -0,0 +1,136 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	type args struct {
+		topology map[string][]string
+	}
+	nodes := []string{"0", "1", "2", "3", "4", "5", "6", "7"}
+	tests := []struct {
+		name string
+		args args
+		want Metrics
+	}{
+		{
+			name: "linear cycle only sends one way",
+			args: args{
+				topology: Linear(nodes),
+			},
+			want: Metrics{
+				Nodes:        8,
+				Edges:        8,
+				Diameter:     7,
+				AvgPath:      4,
+				MinDegree:    1,
+				MaxDegree:    1,
+				Symmetric:    false,
+				Connected:    true,
+				Connectivity: 1,
+			},
+		},
+		{
+			name: "3-tree is cut by its inner nodes",
+			args: args{
+				topology: Tree(nodes, 3),
+			},
+			want: Metrics{
+				Nodes:        8,
+				Edges:        14,
+				Diameter:     4,
+				AvgPath:      2.25,
+				MinDegree:    1,
+				MaxDegree:    4,
+				Symmetric:    true,
+				Connected:    true,
+				Connectivity: 1,
+			},
+		},
+		{
+			name: "complete graph survives all but one failure",
+			args: args{
+				topology: ChordRing(nodes[:5]),
+			},
+			want: Metrics{
+				Nodes:        5,
+				Edges:        20,
+				Diameter:     1,
+				AvgPath:      1,
+				MinDegree:    4,
+				MaxDegree:    4,
+				Symmetric:    true,
+				Connected:    true,
+				Connectivity: 4,
+			},
+		},
+		{
+			name: "3-cube survives two failures",
+			args: args{
+				topology: Hypercube(nodes),
+			},
+			want: Metrics{
+				Nodes:        8,
+				Edges:        24,
+				Diameter:     3,
+				AvgPath:      12.0 / 7,
+				MinDegree:    3,
+				MaxDegree:    3,
+				Symmetric:    true,
+				Connected:    true,
+				Connectivity: 3,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Analyze(tt.args.topology); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Analyze() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeDisconnected(t *testing.T) {
+	type args struct {
+		topology map[string][]string
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{
+			name: "tree with lost parent link",
+			args: args{
+				topology: map[string][]string{
+					"0": {"1"},
+					"1": {"0"},
+					"2": {},
+				},
+			},
+		},
+		{
+			name: "one-way edge",
+			args: args{
+				topology: map[string][]string{
+					"0": {"1"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Connected(tt.args.topology) {
+				t.Errorf("Connected() = true, want false")
+			}
+			if got := Diameter(tt.args.topology); got != -1 {
+				t.Errorf("Diameter() = %v, want -1", got)
+			}
+			if got := VertexConnectivity(tt.args.topology); got != 0 {
+				t.Errorf("VertexConnectivity() = %v, want 0", got)
+			}
+		})
+	}
+}
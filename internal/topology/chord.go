@@ -0,0 +1,14 @@
+package topology
+
+// ChordRing links nodes into a ring and adds shortcuts to the nodes 2, 4,
+// 8, ... positions ahead, so any node is a logarithmic number of hops away.
+func ChordRing(nodes []string) map[string][]string {
+	adj := newAdjacency(len(nodes))
+	for i := range nodes {
+		for dist := 1; dist < len(nodes); dist <<= 1 {
+			adj.link(i, (i+dist)%len(nodes))
+		}
+	}
+
+	return adj.topology(nodes)
+}
@@ -0,0 +1,54 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChordRing(t *testing.T) {
+	type args struct {
+		nodes []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string][]string
+	}{
+		{
+			name: "8 nodes with shortcuts 2 and 4 ahead",
+			args: args{
+				nodes: []string{"0", "1", "2", "3", "4", "5", "6", "7"},
+			},
+			want: map[string][]string{
+				"0": {"1", "2", "4", "6", "7"},
+				"1": {"0", "2", "3", "5", "7"},
+				"2": {"0", "1", "3", "4", "6"},
+				"3": {"1", "2", "4", "5", "7"},
+				"4": {"0", "2", "3", "5", "6"},
+				"5": {"1", "3", "4", "6", "7"},
+				"6": {"0", "2", "4", "5", "7"},
+				"7": {"0", "1", "3", "5", "6"},
+			},
+		},
+		{
+			name: "5 nodes wrap into a complete graph",
+			args: args{
+				nodes: []string{"0", "1", "2", "3", "4"},
+			},
+			want: map[string][]string{
+				"0": {"1", "2", "3", "4"},
+				"1": {"0", "2", "3", "4"},
+				"2": {"0", "1", "3", "4"},
+				"3": {"0", "1", "2", "4"},
+				"4": {"0", "1", "2", "3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChordRing(tt.args.nodes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChordRing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package topology
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Edge is a link from the first node to the second.
+type Edge [2]string
+
+// DOT renders a topology for Graphviz. A symmetric topology becomes an
+// undirected graph with one line per link, anything else a directed graph.
+// labels annotate edges, e.g. with latencies or message counts; for an
+// undirected graph a label may be given for either direction. Nodes and
+// edges are sorted by name, so the same topology always renders the same.
+func DOT(topology map[string][]string, labels map[Edge]string) string {
+	directed := !Symmetric(topology)
+	kind, arrow := "graph", "--"
+	if directed {
+		kind, arrow = "digraph", "->"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s topology {\n", kind)
+	for _, n := range nodeNames(topology) {
+		fmt.Fprintf(&b, "  %s;\n", strconv.Quote(n))
+	}
+	for _, e := range edges(topology, directed) {
+		label, ok := labels[e]
+		if !ok && !directed {
+			label, ok = labels[Edge{e[1], e[0]}]
+		}
+		fmt.Fprintf(&b, "  %s %s %s", strconv.Quote(e[0]), arrow, strconv.Quote(e[1]))
+		if ok {
+			fmt.Fprintf(&b, " [label=%s]", strconv.Quote(label))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// JSON renders a topology in the shape of a Maelstrom topology message,
+// with nodes and neighbors sorted by name and empty neighbor lists kept.
+func JSON(topology map[string][]string) ([]byte, error) {
+	sorted := make(map[string][]string, len(topology))
+	for _, n := range nodeNames(topology) {
+		sorted[n] = make([]string, 0)
+	}
+	for n, nbrs := range topology {
+		sorted[n] = append(sorted[n], nbrs...)
+		sort.Strings(sorted[n])
+	}
+	return json.MarshalIndent(sorted, "", "  ")
+}
+
+// nodeNames lists every node, including those that only appear as
+// neighbors, sorted by name.
+func nodeNames(topology map[string][]string) []string {
+	return newDigraph(topology).names
+}
+
+// edges lists the edges sorted by name; an undirected graph lists each
+// link once, from the smaller name.
+func edges(topology map[string][]string, directed bool) []Edge {
+	g := newDigraph(topology)
+	list := make([]Edge, 0)
+	for a, out := range g.out {
+		for _, b := range out {
+			if directed || a < b {
+				list = append(list, Edge{g.names[a], g.names[b]})
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i][0] != list[j][0] {
+			return list[i][0] < list[j][0]
+		}
+		return list[i][1] < list[j][1]
+	})
+	return list
+}
@@ -0,0 +1,119 @@
+package topology
+
+import (
+	"testing"
+)
+
+func TestDOT(t *testing.T) {
+	type args struct {
+		topology map[string][]string
+		labels   map[Edge]string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "tree renders as undirected graph",
+			args: args{
+				topology: Tree([]string{"0", "1", "2"}, 2),
+			},
+			want: `graph topology {
+  "0";
+  "1";
+  "2";
+  "0" -- "1";
+  "0" -- "2";
+}
+`,
+		},
+		{
+			name: "linear renders as directed graph",
+			args: args{
+				topology: Linear([]string{"0", "1", "2"}),
+			},
+			want: `digraph topology {
+  "0";
+  "1";
+  "2";
+  "0" -> "1";
+  "1" -> "2";
+  "2" -> "0";
+}
+`,
+		},
+		{
+			name: "labels match either direction of a link",
+			args: args{
+				topology: Tree([]string{"0", "1", "2"}, 2),
+				labels: map[Edge]string{
+					{"1", "0"}: "12ms",
+					{"0", "2"}: "40 msgs",
+				},
+			},
+			want: `graph topology {
+  "0";
+  "1";
+  "2";
+  "0" -- "1" [label="12ms"];
+  "0" -- "2" [label="40 msgs"];
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DOT(tt.args.topology, tt.args.labels); got != tt.want {
+				t.Errorf("DOT() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type args struct {
+		topology map[string][]string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "neighbors are sorted and empty lists kept",
+			args: args{
+				topology: map[string][]string{
+					"b": {"c", "a"},
+					"a": {"b"},
+					"c": {"b"},
+					"d": nil,
+				},
+			},
+			want: `{
+  "a": [
+    "b"
+  ],
+  "b": [
+    "a",
+    "c"
+  ],
+  "c": [
+    "b"
+  ],
+  "d": []
+}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSON(tt.args.topology)
+			if err != nil {
+				t.Fatalf("JSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("JSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package topology
+
+// DisjointTrees builds up to k spanning trees that share no edge, so a
+// broken link or a slow node in one tree leaves the others intact. At most
+// half as many trees as there are nodes fit. Nodes are paired as i and
+// i+half; tree i is a double star around the pair i, with every other pair
+// hanging off one center each, and a leftover node of an odd cluster
+// hanging off center i. Every node is a center in at most one tree, so
+// losing it splits no other tree. Trees have a diameter of at most three.
+func DisjointTrees(nodes []string, k int) []map[string][]string {
+	half := len(nodes) / 2
+	if k > half {
+		k = half
+	}
+	if k < 1 {
+		k = 1
+	}
+
+	trees := make([]map[string][]string, 0, k)
+	for i := 0; i < k; i++ {
+		adj := newAdjacency(len(nodes))
+		if half > 0 {
+			adj.link(i, i+half)
+			for j := 0; j < half; j++ {
+				switch {
+				case j > i:
+					adj.link(j, i)
+					adj.link(j+half, i+half)
+				case j < i:
+					adj.link(j, i+half)
+					adj.link(j+half, i)
+				}
+			}
+			if len(nodes)%2 == 1 {
+				adj.link(len(nodes)-1, i)
+			}
+		}
+		trees = append(trees, adj.topology(nodes))
+	}
+
+	return trees
+}
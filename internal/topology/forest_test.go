@@ -0,0 +1,141 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDisjointTrees(t *testing.T) {
+	type args struct {
+		nodes []string
+		k     int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []map[string][]string
+	}{
+		{
+			name: "4 nodes split into 2 trees",
+			args: args{
+				nodes: []string{"0", "1", "2", "3"},
+				k:     2,
+			},
+			want: []map[string][]string{
+				{
+					"0": {"1", "2"},
+					"1": {"0"},
+					"2": {"0", "3"},
+					"3": {"2"},
+				},
+				{
+					"0": {"3"},
+					"1": {"2", "3"},
+					"2": {"1"},
+					"3": {"0", "1"},
+				},
+			},
+		},
+		{
+			name: "odd node hangs off a center, trees are capped",
+			args: args{
+				nodes: []string{"0", "1", "2", "3", "4"},
+				k:     3,
+			},
+			want: []map[string][]string{
+				{
+					"0": {"1", "2", "4"},
+					"1": {"0"},
+					"2": {"0", "3"},
+					"3": {"2"},
+					"4": {"0"},
+				},
+				{
+					"0": {"3"},
+					"1": {"2", "3", "4"},
+					"2": {"1"},
+					"3": {"0", "1"},
+					"4": {"1"},
+				},
+			},
+		},
+		{
+			name: "single node",
+			args: args{
+				nodes: []string{"0"},
+				k:     2,
+			},
+			want: []map[string][]string{
+				{
+					"0": {},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DisjointTrees(tt.args.nodes, tt.args.k); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DisjointTrees() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisjointTreesAreSpanningAndDisjoint(t *testing.T) {
+	type args struct {
+		size int
+		k    int
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "10 nodes fit 5 trees",
+			args: args{size: 10, k: 5},
+			want: 5,
+		},
+		{
+			name: "25 nodes with 3 trees",
+			args: args{size: 25, k: 3},
+			want: 3,
+		},
+		{
+			name: "25 nodes fit 12 trees",
+			args: args{size: 25, k: 20},
+			want: 12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := make([]string, tt.args.size)
+			for i := range nodes {
+				nodes[i] = string(rune('a' + i))
+			}
+
+			trees := DisjointTrees(nodes, tt.args.k)
+			if len(trees) != tt.want {
+				t.Fatalf("DisjointTrees() built %d trees, want %d", len(trees), tt.want)
+			}
+
+			used := make(map[[2]string]int)
+			for i, tree := range trees {
+				m := Analyze(tree)
+				if !m.Connected || !m.Symmetric || m.Edges != 2*(tt.args.size-1) || m.Diameter > 3 {
+					t.Errorf("tree %d is not a spanning tree of diameter 3: %+v", i, m)
+				}
+				for n, nbrs := range tree {
+					for _, nbr := range nbrs {
+						if n < nbr {
+							if prev, ok := used[[2]string{n, nbr}]; ok {
+								t.Errorf("edge %s-%s is in trees %d and %d", n, nbr, prev, i)
+							}
+							used[[2]string{n, nbr}] = i
+						}
+					}
+				}
+			}
+		})
+	}
+}
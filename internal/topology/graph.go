@@ -0,0 +1,68 @@
+package topology
+
+import "sort"
+
+// adjacency is an undirected graph over node indexes.
+type adjacency []map[int]bool
+
+func newAdjacency(size int) adjacency {
+	adj := make(adjacency, size)
+	for i := range adj {
+		adj[i] = make(map[int]bool)
+	}
+	return adj
+}
+
+func (adj adjacency) link(a, b int) {
+	if a == b {
+		return
+	}
+	adj[a][b] = true
+	adj[b][a] = true
+}
+
+func (adj adjacency) unlink(a, b int) {
+	delete(adj[a], b)
+	delete(adj[b], a)
+}
+
+// connected reports whether every node can reach every other node.
+func (adj adjacency) connected() bool {
+	if len(adj) == 0 {
+		return true
+	}
+	visited := make([]bool, len(adj))
+	visited[0] = true
+	queue := []int{0}
+	count := 1
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for next := range adj[cur] {
+			if !visited[next] {
+				visited[next] = true
+				count++
+				queue = append(queue, next)
+			}
+		}
+	}
+	return count == len(adj)
+}
+
+// topology names the nodes, listing neighbors in the order of nodes.
+func (adj adjacency) topology(nodes []string) map[string][]string {
+	topology := make(map[string][]string)
+	for i, n := range nodes {
+		idx := make([]int, 0, len(adj[i]))
+		for j := range adj[i] {
+			idx = append(idx, j)
+		}
+		sort.Ints(idx)
+
+		topology[n] = make([]string, 0, len(idx))
+		for _, j := range idx {
+			topology[n] = append(topology[n], nodes[j])
+		}
+	}
+	return topology
+}
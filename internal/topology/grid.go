@@ -0,0 +1,21 @@
+package topology
+
+// Grid lays nodes out row by row in rows of width and links every node to
+// the nodes above, below, left and right of it. The last row may be short.
+// A width below one puts all nodes in a single row.
+func Grid(nodes []string, width int) map[string][]string {
+	if width < 1 {
+		width = len(nodes) + 1
+	}
+	adj := newAdjacency(len(nodes))
+	for i := range nodes {
+		if i%width != width-1 && i+1 < len(nodes) {
+			adj.link(i, i+1)
+		}
+		if i+width < len(nodes) {
+			adj.link(i, i+width)
+		}
+	}
+
+	return adj.topology(nodes)
+}
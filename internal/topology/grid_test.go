@@ -0,0 +1,55 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	type args struct {
+		nodes []string
+		width int
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string][]string
+	}{
+		{
+			name: "8 nodes to grid of width 3 with a short last row",
+			args: args{
+				nodes: []string{"0", "1", "2", "3", "4", "5", "6", "7"},
+				width: 3,
+			},
+			want: map[string][]string{
+				"0": {"1", "3"},
+				"1": {"0", "2", "4"},
+				"2": {"1", "5"},
+				"3": {"0", "4", "6"},
+				"4": {"1", "3", "5", "7"},
+				"5": {"2", "4"},
+				"6": {"3", "7"},
+				"7": {"4", "6"},
+			},
+		},
+		{
+			name: "zero width puts nodes in a single row",
+			args: args{
+				nodes: []string{"0", "1", "2"},
+				width: 0,
+			},
+			want: map[string][]string{
+				"0": {"1"},
+				"1": {"0", "2"},
+				"2": {"1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Grid(tt.args.nodes, tt.args.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Grid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
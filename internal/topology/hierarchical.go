@@ -0,0 +1,40 @@
+package topology
+
+// Hierarchical splits nodes into consecutive groups of groupSize, the last
+// one possibly shorter. Nodes are fully connected within their group, and
+// the first gateways nodes of every group act as its gateways: gateway i of
+// each group links to gateway i of every other group. Any two nodes are at
+// most three hops apart, up to the gateway, across, and down, and with
+// several gateways the groups stay connected when one of them fails.
+func Hierarchical(nodes []string, groupSize int, gateways int) map[string][]string {
+	if groupSize < 1 {
+		groupSize = len(nodes)
+	}
+	if gateways < 1 {
+		gateways = 1
+	}
+	if gateways > groupSize {
+		gateways = groupSize
+	}
+
+	adj := newAdjacency(len(nodes))
+	for start := 0; start < len(nodes); start += groupSize {
+		end := start + groupSize
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		for a := start; a < end; a++ {
+			for b := a + 1; b < end; b++ {
+				adj.link(a, b)
+			}
+		}
+
+		for g := 0; g < gateways && start+g < end; g++ {
+			for other := end; other+g < len(nodes); other += groupSize {
+				adj.link(start+g, other+g)
+			}
+		}
+	}
+
+	return adj.topology(nodes)
+}
@@ -0,0 +1,92 @@
+package topology
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHierarchical(t *testing.T) {
+	type args struct {
+		nodes     []string
+		groupSize int
+		gateways  int
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string][]string
+	}{
+		{
+			name: "7 nodes in groups of 3 with 2 gateways",
+			args: args{
+				nodes:     []string{"0", "1", "2", "3", "4", "5", "6"},
+				groupSize: 3,
+				gateways:  2,
+			},
+			want: map[string][]string{
+				"0": {"1", "2", "3", "6"},
+				"1": {"0", "2", "4"},
+				"2": {"0", "1"},
+				"3": {"0", "4", "5", "6"},
+				"4": {"1", "3", "5"},
+				"5": {"3", "4"},
+				"6": {"0", "3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Hierarchical(tt.args.nodes, tt.args.groupSize, tt.args.gateways); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hierarchical() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHierarchicalDiameter(t *testing.T) {
+	type args struct {
+		size      int
+		groupSize int
+		gateways  int
+	}
+	tests := []struct {
+		name string
+		args args
+		want Metrics
+	}{
+		{
+			name: "25 nodes in 5 racks of 5 with 2 gateways",
+			args: args{size: 25, groupSize: 5, gateways: 2},
+			want: Metrics{Nodes: 25, Edges: 140, Diameter: 3, MinDegree: 4, MaxDegree: 8, Connectivity: 2},
+		},
+		{
+			name: "30 nodes in racks of 4 with a short last rack",
+			args: args{size: 30, groupSize: 4, gateways: 1},
+			want: Metrics{Nodes: 30, Edges: 142, Diameter: 3, MinDegree: 1, MaxDegree: 10, Connectivity: 1},
+		},
+		{
+			name: "single group is fully connected",
+			args: args{size: 6, groupSize: 10, gateways: 3},
+			want: Metrics{Nodes: 6, Edges: 30, Diameter: 1, MinDegree: 5, MaxDegree: 5, Connectivity: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := make([]string, tt.args.size)
+			for i := range nodes {
+				nodes[i] = fmt.Sprintf("n%d", i)
+			}
+
+			got := Analyze(Hierarchical(nodes, tt.args.groupSize, tt.args.gateways))
+			if !got.Symmetric || !got.Connected || got.Diameter > 3 {
+				t.Errorf("Hierarchical() = %+v, want symmetric, connected and diameter at most 3", got)
+			}
+			// path lengths are covered by the diameter
+			got.AvgPath, got.Symmetric, got.Connected = 0, false, false
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hierarchical() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
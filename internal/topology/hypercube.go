@@ -0,0 +1,18 @@
+package topology
+
+// Hypercube links nodes whose indexes differ in exactly one bit. When the
+// number of nodes is not a power of two the missing corners are skipped;
+// the cube stays connected since clearing the highest bit always leads to
+// an existing node.
+func Hypercube(nodes []string) map[string][]string {
+	adj := newAdjacency(len(nodes))
+	for i := range nodes {
+		for bit := 1; bit < len(nodes); bit <<= 1 {
+			if j := i ^ bit; j < len(nodes) {
+				adj.link(i, j)
+			}
+		}
+	}
+
+	return adj.topology(nodes)
+}
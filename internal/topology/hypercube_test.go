@@ -0,0 +1,55 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHypercube(t *testing.T) {
+	type args struct {
+		nodes []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string][]string
+	}{
+		{
+			name: "8 nodes form a 3-cube",
+			args: args{
+				nodes: []string{"0", "1", "2", "3", "4", "5", "6", "7"},
+			},
+			want: map[string][]string{
+				"0": {"1", "2", "4"},
+				"1": {"0", "3", "5"},
+				"2": {"0", "3", "6"},
+				"3": {"1", "2", "7"},
+				"4": {"0", "5", "6"},
+				"5": {"1", "4", "7"},
+				"6": {"2", "4", "7"},
+				"7": {"3", "5", "6"},
+			},
+		},
+		{
+			name: "6 nodes skip missing corners",
+			args: args{
+				nodes: []string{"0", "1", "2", "3", "4", "5"},
+			},
+			want: map[string][]string{
+				"0": {"1", "2", "4"},
+				"1": {"0", "3", "5"},
+				"2": {"0", "3"},
+				"3": {"1", "2"},
+				"4": {"0", "5"},
+				"5": {"1", "4"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Hypercube(tt.args.nodes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hypercube() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,10 @@ func Linear(nodes []string) map[string][]string {
 		next := (i + 1) % len(nodes)
 		topology[n] = []string{nodes[next]}
 	}
+	// a single node would be its own successor
+	if len(nodes) == 1 {
+		topology[nodes[0]] = []string{}
+	}
 
 	return topology
 }
@@ -30,6 +30,15 @@ func TestLinear(t *testing.T) {
 				"7": {"0"},
 			},
 		},
+		{
+			name: "single node has no neighbors",
+			args: args{
+				nodes: []string{"0"},
+			},
+			want: map[string][]string{
+				"0": {},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
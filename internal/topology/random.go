@@ -0,0 +1,85 @@
+package topology
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// RandomRegular returns a connected random graph in which every node has
+// degree neighbors. The same seed gives the same graph. When degree and the
+// number of nodes are both odd no such graph exists, and one node gets one
+// neighbor less. A degree of at least the number of nodes gives a complete
+// graph.
+func RandomRegular(nodes []string, degree int, seed int64) map[string][]string {
+	size := len(nodes)
+	if degree >= size {
+		degree = size - 1
+	}
+	adj := newAdjacency(size)
+	if degree <= 0 {
+		return adj.topology(nodes)
+	}
+
+	// start from a circulant graph, which is regular and connected
+	for i := 0; i < size; i++ {
+		for dist := 1; dist <= degree/2; dist++ {
+			adj.link(i, (i+dist)%size)
+		}
+	}
+	if degree%2 == 1 {
+		half := size / 2
+		for i := 0; i < half; i++ {
+			adj.link(i, i+half)
+		}
+	}
+
+	// then shuffle it with degree preserving edge swaps
+	rnd := rand.New(rand.NewSource(seed))
+	edges := make([][2]int, 0, size*degree/2)
+	for a := range adj {
+		for b := range adj[a] {
+			if a < b {
+				edges = append(edges, [2]int{a, b})
+			}
+		}
+	}
+	// map iteration order is random, the seed alone must decide the result
+	sortEdges(edges)
+
+	for swaps := 0; swaps < 10*len(edges); swaps++ {
+		x, y := rnd.Intn(len(edges)), rnd.Intn(len(edges))
+		a, b := edges[x][0], edges[x][1]
+		c, d := edges[y][0], edges[y][1]
+		if rnd.Intn(2) == 0 {
+			c, d = d, c
+		}
+		if a == c || a == d || b == c || b == d || adj[a][d] || adj[c][b] {
+			continue
+		}
+
+		adj.unlink(a, b)
+		adj.unlink(c, d)
+		adj.link(a, d)
+		adj.link(c, b)
+		if !adj.connected() {
+			adj.unlink(a, d)
+			adj.unlink(c, b)
+			adj.link(a, b)
+			adj.link(c, d)
+			continue
+		}
+		edges[x] = [2]int{a, d}
+		edges[y] = [2]int{c, b}
+	}
+
+	return adj.topology(nodes)
+}
+
+func sortEdges(edges [][2]int) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+}
@@ -0,0 +1,86 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRandomRegular(t *testing.T) {
+	type args struct {
+		nodes  []string
+		degree int
+		seed   int64
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[int]int // degree -> number of nodes with it
+	}{
+		{
+			name: "8 nodes of degree 3",
+			args: args{
+				nodes:  []string{"0", "1", "2", "3", "4", "5", "6", "7"},
+				degree: 3,
+				seed:   1,
+			},
+			want: map[int]int{3: 8},
+		},
+		{
+			name: "7 nodes of odd degree leave one node short",
+			args: args{
+				nodes:  []string{"0", "1", "2", "3", "4", "5", "6"},
+				degree: 3,
+				seed:   2,
+			},
+			want: map[int]int{3: 6, 2: 1},
+		},
+		{
+			name: "degree above cluster size gives complete graph",
+			args: args{
+				nodes:  []string{"0", "1", "2", "3"},
+				degree: 5,
+				seed:   3,
+			},
+			want: map[int]int{3: 4},
+		},
+		{
+			name: "25 nodes of degree 2 form one cycle",
+			args: args{
+				nodes:  []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "20", "21", "22", "23", "24"},
+				degree: 2,
+				seed:   4,
+			},
+			want: map[int]int{2: 25},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RandomRegular(tt.args.nodes, tt.args.degree, tt.args.seed)
+
+			degrees := make(map[int]int)
+			adj := newAdjacency(len(tt.args.nodes))
+			index := make(map[string]int)
+			for i, n := range tt.args.nodes {
+				index[n] = i
+			}
+			for n, nbrs := range got {
+				degrees[len(nbrs)]++
+				for _, nbr := range nbrs {
+					adj.link(index[n], index[nbr])
+				}
+			}
+			if !reflect.DeepEqual(degrees, tt.want) {
+				t.Errorf("RandomRegular() degrees = %v, want %v", degrees, tt.want)
+			}
+			if !reflect.DeepEqual(adj.topology(tt.args.nodes), got) {
+				t.Errorf("RandomRegular() = %v, is not symmetric", got)
+			}
+			if !adj.connected() {
+				t.Errorf("RandomRegular() = %v, is not connected", got)
+			}
+			if again := RandomRegular(tt.args.nodes, tt.args.degree, tt.args.seed); !reflect.DeepEqual(got, again) {
+				t.Errorf("RandomRegular() = %v, then %v for the same seed", got, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Input is what a generator may use besides the list of nodes.
+type Input struct {
+	Given   map[string][]string                 // topology suggested by Maelstrom
+	Latency map[string]map[string]time.Duration // measured round trips, see Weighted
+}
+
+// Generator builds neighbors for every node.
+type Generator func(nodes []string, in Input) map[string][]string
+
+// Factory turns the arguments of a spec into a generator, rejecting
+// arguments it cannot build a topology from.
+type Factory func(args []string) (Generator, error)
+
+// Traits describe what a strategy needs and what it builds.
+type Traits struct {
+	NeedsGiven   bool // passes Input.Given through
+	NeedsLatency bool // built from Input.Latency
+	Directed     bool // neighbors may be one-way, see ValidateDirected
+}
+
+// Registry maps strategy names to generators. Specs have the form
+// name[:arg[:arg...]], e.g. "tree:5" or "grid:4".
+type Registry struct {
+	factories map[string]Factory
+	traits    map[string]Traits
+}
+
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory), traits: make(map[string]Traits)}
+}
+
+// Register adds a strategy that builds symmetric neighbors from the nodes
+// and the given topology alone.
+func (r *Registry) Register(name string, factory Factory) {
+	r.RegisterWith(name, Traits{}, factory)
+}
+
+func (r *Registry) RegisterWith(name string, traits Traits, factory Factory) {
+	r.factories[name] = factory
+	r.traits[name] = traits
+}
+
+// Names lists the registered strategies in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse resolves a spec to a strategy. Unknown names and invalid arguments
+// are reported right away, before any node list is known.
+func (r *Registry) Parse(spec string) (*Strategy, error) {
+	parts := strings.Split(spec, ":")
+	name, args := parts[0], parts[1:]
+
+	factory, ok := r.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown topology %q, expected one of %s", spec, strings.Join(r.Names(), ", "))
+	}
+	generate, err := factory(args)
+	if err != nil {
+		return nil, fmt.Errorf("topology %q: %w", spec, err)
+	}
+
+	return &Strategy{Spec: spec, Name: name, Traits: r.traits[name], generate: generate}, nil
+}
+
+// Strategy is a parsed spec.
+type Strategy struct {
+	Spec string
+	Name string
+	Traits
+	generate Generator
+}
+
+// Build generates neighbors for nodes and validates the result.
+func (s *Strategy) Build(nodes []string, in Input) (map[string][]string, error) {
+	topology := s.Generate(nodes, in)
+	if err := s.Validate(topology, nodes); err != nil {
+		return nil, err
+	}
+	return topology, nil
+}
+
+// Generate returns the neighbors the strategy builds, without validating
+// them, e.g. to look at what went wrong.
+func (s *Strategy) Generate(nodes []string, in Input) map[string][]string {
+	return s.generate(nodes, in)
+}
+
+// Validate checks topology with ValidateDirected for directed strategies
+// and with Validate for the rest.
+func (s *Strategy) Validate(topology map[string][]string, nodes []string) error {
+	validate := Validate
+	if s.Directed {
+		validate = ValidateDirected
+	}
+	if err := validate(topology, nodes); err != nil {
+		return fmt.Errorf("topology %q: %w", s.Spec, err)
+	}
+	return nil
+}
+
+// Validate checks that topology covers exactly nodes, with no node listing
+// itself, and that it is symmetric and connected.
+func Validate(topology map[string][]string, nodes []string) error {
+	if err := validateNodes(topology, nodes); err != nil {
+		return err
+	}
+	// nodes are checked in order so the same topology always reports the
+	// same error
+	for _, n := range nodes {
+		for _, nbr := range topology[n] {
+			if !contains(topology[nbr], n) {
+				return fmt.Errorf("node %s lists %s, but not the other way round", n, nbr)
+			}
+		}
+	}
+
+	g := newDigraph(topology)
+	if len(g.out) > 0 {
+		for i, d := range g.distances(0) {
+			if d < 0 {
+				// distances skips the source itself
+				return fmt.Errorf("node %s cannot reach %s", g.names[0], g.names[i+1])
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateDirected checks what Validate does for a topology whose links may
+// be one-way, such as Linear: every node must still reach every other node
+// along its links.
+func ValidateDirected(topology map[string][]string, nodes []string) error {
+	if err := validateNodes(topology, nodes); err != nil {
+		return err
+	}
+
+	g := newDigraph(topology)
+	for src := range g.out {
+		for i, d := range g.distances(src) {
+			if d < 0 {
+				dst := i
+				if i >= src {
+					dst++ // distances skips the source itself
+				}
+				return fmt.Errorf("node %s cannot reach %s", g.names[src], g.names[dst])
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateNodes checks that topology covers exactly nodes and that no node
+// lists itself or a node outside the cluster.
+func validateNodes(topology map[string][]string, nodes []string) error {
+	known := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if known[n] {
+			return fmt.Errorf("node %s is listed twice", n)
+		}
+		known[n] = true
+	}
+
+	for _, n := range nodes {
+		if _, ok := topology[n]; !ok {
+			return fmt.Errorf("node %s has no entry", n)
+		}
+	}
+	for n := range topology {
+		if !known[n] {
+			return fmt.Errorf("node %s is not in the cluster", n)
+		}
+	}
+	for _, n := range nodes {
+		for _, nbr := range topology[n] {
+			switch {
+			case !known[nbr]:
+				return fmt.Errorf("node %s lists %s, which is not in the cluster", n, nbr)
+			case nbr == n:
+				return fmt.Errorf("node %s lists itself", n)
+			}
+		}
+	}
+	return nil
+}
+
+// Undirected adds the reverse of every edge and drops self-loops. Neighbors
+// are sorted by name.
+func Undirected(topology map[string][]string) map[string][]string {
+	undirected := make(map[string][]string, len(topology))
+	for n := range topology {
+		undirected[n] = make([]string, 0)
+	}
+	link := func(a, b string) {
+		if a != b && !contains(undirected[a], b) {
+			undirected[a] = append(undirected[a], b)
+		}
+	}
+	for n, nbrs := range topology {
+		for _, nbr := range nbrs {
+			link(n, nbr)
+			link(nbr, n)
+		}
+	}
+	for _, nbrs := range undirected {
+		sort.Strings(nbrs)
+	}
+	return undirected
+}
+
+func contains(nodes []string, node string) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// intArgs parses every argument as a positive integer; names describes
+// them for errors. Only the first required arguments must be present.
+func intArgs(args []string, required int, names ...string) ([]int, error) {
+	if len(args) < required || len(args) > len(names) {
+		return nil, fmt.Errorf("expected %s", usage(required, names))
+	}
+	values := make([]int, len(args))
+	for i, arg := range args {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%s must be a positive integer, got %q", names[i], arg)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func usage(required int, names []string) string {
+	if len(names) == 0 {
+		return "no arguments"
+	}
+	var b strings.Builder
+	for i, name := range names {
+		sep := ":"
+		if i == 0 {
+			sep = ""
+		}
+		if i >= required {
+			fmt.Fprintf(&b, "[%s<%s>]", sep, name)
+		} else {
+			fmt.Fprintf(&b, "%s<%s>", sep, name)
+		}
+	}
+	return b.String()
+}
+
+// simple registers a generator that only needs the nodes.
+func simple(build func(nodes []string) map[string][]string) Factory {
+	return func(args []string) (Generator, error) {
+		if _, err := intArgs(args, 0); err != nil {
+			return nil, err
+		}
+		return func(nodes []string, _ Input) map[string][]string {
+			return build(nodes)
+		}, nil
+	}
+}
+
+var registry = defaultRegistry()
+
+func defaultRegistry() *Registry {
+	r := NewRegistry()
+	r.RegisterWith("maelstrom", Traits{NeedsGiven: true}, func(args []string) (Generator, error) {
+		if _, err := intArgs(args, 0); err != nil {
+			return nil, err
+		}
+		return func(_ []string, in Input) map[string][]string {
+			return in.Given
+		}, nil
+	})
+	// linear only reaches forward, ring adds the reverse edges
+	r.RegisterWith("linear", Traits{Directed: true}, simple(Linear))
+	r.Register("ring", simple(func(nodes []string) map[string][]string {
+		return Undirected(Linear(nodes))
+	}))
+	r.Register("tree", func(args []string) (Generator, error) {
+		v, err := intArgs(args, 1, "children")
+		if err != nil {
+			return nil, err
+		}
+		return func(nodes []string, _ Input) map[string][]string {
+			return Tree(nodes, v[0])
+		}, nil
+	})
+	r.Register("grid", func(args []string) (Generator, error) {
+		v, err := intArgs(args, 1, "width")
+		if err != nil {
+			return nil, err
+		}
+		return func(nodes []string, _ Input) map[string][]string {
+			return Grid(nodes, v[0])
+		}, nil
+	})
+	r.Register("hierarchical", func(args []string) (Generator, error) {
+		v, err := intArgs(args, 1, "group size", "gateways")
+		if err != nil {
+			return nil, err
+		}
+		gateways := 2
+		if len(v) > 1 {
+			gateways = v[1]
+		}
+		return func(nodes []string, _ Input) map[string][]string {
+			return Hierarchical(nodes, v[0], gateways)
+		}, nil
+	})
+	r.Register("hypercube", simple(Hypercube))
+	r.Register("chord", simple(ChordRing))
+	r.Register("random", func(args []string) (Generator, error) {
+		// every node must build the same graph, so the seed is part of the spec
+		v, err := intArgs(args, 1, "degree", "seed")
+		if err != nil {
+			return nil, err
+		}
+		seed := int64(1)
+		if len(v) > 1 {
+			seed = int64(v[1])
+		}
+		return func(nodes []string, _ Input) map[string][]string {
+			return RandomRegular(nodes, v[0], seed)
+		}, nil
+	})
+	r.RegisterWith("weighted", Traits{NeedsLatency: true}, func(args []string) (Generator, error) {
+		v, err := intArgs(args, 0, "max degree")
+		if err != nil {
+			return nil, err
+		}
+		maxDegree := 0
+		if len(v) > 0 {
+			maxDegree = v[0]
+		}
+		return func(nodes []string, in Input) map[string][]string {
+			return Weighted(nodes, in.Latency, maxDegree)
+		}, nil
+	})
+	return r
+}
+
+// Register adds a strategy to the default registry.
+func Register(name string, factory Factory) {
+	registry.Register(name, factory)
+}
+
+// RegisterWith adds a strategy with traits to the default registry.
+func RegisterWith(name string, traits Traits, factory Factory) {
+	registry.RegisterWith(name, traits, factory)
+}
+
+// Parse resolves a spec with the default registry.
+func Parse(spec string) (*Strategy, error) {
+	return registry.Parse(spec)
+}
@@ -0,0 +1,266 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	type args struct {
+		spec string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		want        string
+		wantLatency bool
+		wantErr     string
+	}{
+		{
+			name: "tree with children",
+			args: args{spec: "tree:5"},
+			want: "tree",
+		},
+		{
+			name: "random with optional seed",
+			args: args{spec: "random:3:42"},
+			want: "random",
+		},
+		{
+			name:        "weighted needs latency",
+			args:        args{spec: "weighted:3"},
+			want:        "weighted",
+			wantLatency: true,
+		},
+		{
+			name:    "unknown name",
+			args:    args{spec: "star"},
+			wantErr: `unknown topology "star", expected one of chord, grid, hierarchical, hypercube, linear, maelstrom, random, ring, tree, weighted`,
+		},
+		{
+			name:    "missing argument",
+			args:    args{spec: "tree"},
+			wantErr: `topology "tree": expected <children>`,
+		},
+		{
+			name:    "invalid argument",
+			args:    args{spec: "tree:0"},
+			wantErr: `topology "tree:0": children must be a positive integer, got "0"`,
+		},
+		{
+			name:    "too many arguments",
+			args:    args{spec: "random:3:42:1"},
+			wantErr: `topology "random:3:42:1": expected <degree>[:<seed>]`,
+		},
+		{
+			name:    "argument for strategy without any",
+			args:    args{spec: "hypercube:2"},
+			wantErr: `topology "hypercube:2": expected no arguments`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.args.spec)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("Parse() name = %v, want %v", got.Name, tt.want)
+			}
+			if got.NeedsLatency != tt.wantLatency {
+				t.Errorf("Parse() NeedsLatency = %v, want %v", got.NeedsLatency, tt.wantLatency)
+			}
+		})
+	}
+}
+
+func TestStrategyBuild(t *testing.T) {
+	type args struct {
+		spec  string
+		nodes []string
+		in    Input
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string][]string
+		wantErr string
+	}{
+		{
+			name: "ring links both ways",
+			args: args{
+				spec:  "ring",
+				nodes: []string{"0", "1", "2", "3"},
+			},
+			want: map[string][]string{
+				"0": {"1", "3"},
+				"1": {"0", "2"},
+				"2": {"1", "3"},
+				"3": {"0", "2"},
+			},
+		},
+		{
+			name: "linear only links forward",
+			args: args{
+				spec:  "linear",
+				nodes: []string{"0", "1", "2"},
+			},
+			want: map[string][]string{
+				"0": {"1"},
+				"1": {"2"},
+				"2": {"0"},
+			},
+		},
+		{
+			name: "single node is a valid topology",
+			args: args{
+				spec:  "ring",
+				nodes: []string{"0"},
+			},
+			want: map[string][]string{
+				"0": {},
+			},
+		},
+		{
+			name: "given topology is passed through",
+			args: args{
+				spec:  "maelstrom",
+				nodes: []string{"0", "1"},
+				in: Input{Given: map[string][]string{
+					"0": {"1"},
+					"1": {"0"},
+				}},
+			},
+			want: map[string][]string{
+				"0": {"1"},
+				"1": {"0"},
+			},
+		},
+		{
+			name: "given topology with unknown node",
+			args: args{
+				spec:  "maelstrom",
+				nodes: []string{"0", "1"},
+				in: Input{Given: map[string][]string{
+					"0": {"1", "7"},
+					"1": {"0"},
+				}},
+			},
+			wantErr: `topology "maelstrom": node 0 lists 7, which is not in the cluster`,
+		},
+		{
+			name: "given topology missing a node",
+			args: args{
+				spec:  "maelstrom",
+				nodes: []string{"0", "1", "2"},
+				in: Input{Given: map[string][]string{
+					"0": {"1"},
+					"1": {"0"},
+				}},
+			},
+			wantErr: `topology "maelstrom": node 2 has no entry`,
+		},
+		{
+			name: "given topology that is one-way",
+			args: args{
+				spec:  "maelstrom",
+				nodes: []string{"0", "1"},
+				in: Input{Given: map[string][]string{
+					"0": {"1"},
+					"1": {},
+				}},
+			},
+			wantErr: `topology "maelstrom": node 0 lists 1, but not the other way round`,
+		},
+		{
+			name: "given topology that is split",
+			args: args{
+				spec:  "maelstrom",
+				nodes: []string{"0", "1", "2"},
+				in: Input{Given: map[string][]string{
+					"0": {"1"},
+					"1": {"0"},
+					"2": {},
+				}},
+			},
+			wantErr: `topology "maelstrom": node 0 cannot reach 2`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.args.spec)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := s.Build(tt.args.nodes, tt.args.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Build() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDirected(t *testing.T) {
+	type args struct {
+		topology map[string][]string
+		nodes    []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr string
+	}{
+		{
+			name: "one-way ring",
+			args: args{
+				topology: Linear([]string{"0", "1", "2", "3"}),
+				nodes:    []string{"0", "1", "2", "3"},
+			},
+		},
+		{
+			name: "chain cannot reach back",
+			args: args{
+				topology: map[string][]string{"0": {"1"}, "1": {"2"}, "2": {}},
+				nodes:    []string{"0", "1", "2"},
+			},
+			wantErr: "node 1 cannot reach 0",
+		},
+		{
+			name: "unknown neighbor",
+			args: args{
+				topology: map[string][]string{"0": {"1"}, "1": {"7"}},
+				nodes:    []string{"0", "1"},
+			},
+			wantErr: "node 1 lists 7, which is not in the cluster",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDirected(tt.args.topology, tt.args.nodes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateDirected() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateDirected() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package topology
+
+import (
+	"math"
+	"time"
+)
+
+const unknownLatency = time.Duration(math.MaxInt64)
+
+// Weighted builds a spanning tree that prefers fast links, using Prim's
+// algorithm from the first node. latency[a][b] is the round trip measured
+// from a to b; the two directions of a link are averaged and a link not
+// measured from either side is picked last. With maxDegree of two or more
+// no node gets more neighbors than that, which keeps a single fast node
+// from becoming the hub of the whole cluster. Ties go to the node that
+// comes first in nodes, so every node builds the same tree from the same
+// measurements.
+func Weighted(nodes []string, latency map[string]map[string]time.Duration, maxDegree int) map[string][]string {
+	adj := newAdjacency(len(nodes))
+	if len(nodes) == 0 {
+		return adj.topology(nodes)
+	}
+
+	cost := func(i, j int) time.Duration {
+		there, okThere := latency[nodes[i]][nodes[j]]
+		back, okBack := latency[nodes[j]][nodes[i]]
+		switch {
+		case okThere && okBack:
+			return (there + back) / 2
+		case okThere:
+			return there
+		case okBack:
+			return back
+		default:
+			return unknownLatency
+		}
+	}
+
+	inTree := make([]bool, len(nodes))
+	inTree[0] = true
+	for added := 1; added < len(nodes); added++ {
+		from, to := -1, -1
+		var best time.Duration
+		for i := range nodes {
+			if !inTree[i] || (maxDegree >= 2 && len(adj[i]) >= maxDegree) {
+				continue
+			}
+			for j := range nodes {
+				if inTree[j] {
+					continue
+				}
+				if c := cost(i, j); to < 0 || c < best {
+					from, to, best = i, j, c
+				}
+			}
+		}
+		adj.link(from, to)
+		inTree[to] = true
+	}
+
+	return adj.topology(nodes)
+}
@@ -0,0 +1,91 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWeighted(t *testing.T) {
+	type args struct {
+		nodes     []string
+		latency   map[string]map[string]time.Duration
+		maxDegree int
+	}
+	// node 0 is fast to everyone, 1-2 and 2-3 are fast links too
+	latency := map[string]map[string]time.Duration{
+		"0": {"1": 1 * time.Millisecond, "2": 2 * time.Millisecond, "3": 3 * time.Millisecond},
+		"1": {"0": 1 * time.Millisecond, "2": 2 * time.Millisecond, "3": 50 * time.Millisecond},
+		"2": {"0": 2 * time.Millisecond, "1": 2 * time.Millisecond, "3": 4 * time.Millisecond},
+		"3": {"0": 3 * time.Millisecond, "1": 50 * time.Millisecond, "2": 4 * time.Millisecond},
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string][]string
+	}{
+		{
+			name: "unbounded degree gives minimum spanning tree",
+			args: args{
+				nodes:   []string{"0", "1", "2", "3"},
+				latency: latency,
+			},
+			want: map[string][]string{
+				"0": {"1", "2", "3"},
+				"1": {"0"},
+				"2": {"0"},
+				"3": {"0"},
+			},
+		},
+		{
+			name: "degree bound pushes nodes to next best links",
+			args: args{
+				nodes:     []string{"0", "1", "2", "3"},
+				latency:   latency,
+				maxDegree: 2,
+			},
+			want: map[string][]string{
+				"0": {"1", "2"},
+				"1": {"0"},
+				"2": {"0", "3"},
+				"3": {"2"},
+			},
+		},
+		{
+			name: "one-sided and averaged measurements",
+			args: args{
+				nodes: []string{"0", "1", "2"},
+				latency: map[string]map[string]time.Duration{
+					"0": {"1": 9 * time.Millisecond, "2": 1 * time.Millisecond},
+					"1": {"0": 1 * time.Millisecond},
+					"2": {"1": 2 * time.Millisecond},
+				},
+			},
+			want: map[string][]string{
+				"0": {"2"},
+				"1": {"2"},
+				"2": {"0", "1"},
+			},
+		},
+		{
+			name: "no measurements fall back to node order",
+			args: args{
+				nodes:     []string{"0", "1", "2", "3"},
+				maxDegree: 2,
+			},
+			want: map[string][]string{
+				"0": {"1", "2"},
+				"1": {"0", "3"},
+				"2": {"0"},
+				"3": {"1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Weighted(tt.args.nodes, tt.args.latency, tt.args.maxDegree); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Weighted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}